@@ -0,0 +1,169 @@
+package gowl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	AccessLogCombined = "combined"
+	AccessLogJSON     = "json"
+	AccessLogLogfmt   = "logfmt"
+)
+
+// AccessLogEntry
+type AccessLogEntry struct {
+	Time      time.Time     `json:"time"`
+	ClientIP  string        `json:"client_ip"`
+	Method    string        `json:"method"`
+	URI       string        `json:"uri"`
+	Proto     string        `json:"proto"`
+	Status    int           `json:"status"`
+	Size      int64         `json:"size"`
+	Duration  time.Duration `json:"duration"`
+	Referer   string        `json:"referer"`
+	UserAgent string        `json:"user_agent"`
+	RequestID string        `json:"request_id"`
+	Route     string        `json:"route"`
+}
+
+// accessLogger
+type accessLogger struct {
+	mu         sync.Mutex
+	writer     io.Writer
+	format     func(e *AccessLogEntry) string
+	sampleRate float64
+}
+
+func (l *accessLogger) log(e *AccessLogEntry) {
+	// server errors are never sampled out
+	if l.sampleRate < 1 && e.Status < http.StatusInternalServerError && rand.Float64() >= l.sampleRate {
+		return
+	}
+	line := l.format(e)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := io.WriteString(l.writer, line+"\n"); err != nil {
+		Error.Print(err)
+	}
+}
+
+func newAccessLogger(config *Config) (*accessLogger, error) {
+	l := &accessLogger{
+		writer:     config.AccessLogWriter,
+		sampleRate: config.AccessLogSampleRate,
+	}
+
+	if l.writer == nil {
+		switch config.AccessLog {
+		case "":
+			return nil, nil
+		case "stdout":
+			l.writer = stdout
+		case "stderr":
+			l.writer = stderr
+		default:
+			f, err := os.OpenFile(config.AccessLog, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+			if err != nil {
+				return nil, errors.Wrap(err, "gowl: cannot open access log")
+			}
+			l.writer = f
+		}
+	}
+
+	switch config.AccessLogFormat {
+	case "", AccessLogCombined:
+		l.format = formatCombined
+	case AccessLogJSON:
+		l.format = formatJSON
+	case AccessLogLogfmt:
+		l.format = formatLogfmt
+	default:
+		return nil, fmt.Errorf(`gowl: unknown access log format "%s"`, config.AccessLogFormat)
+	}
+	return l, nil
+}
+
+func newAccessLogEntry(request *Request, w *responseWriter, statusCode int, start time.Time) *AccessLogEntry {
+	if w.statusCode != 0 {
+		statusCode = w.statusCode
+	}
+	return &AccessLogEntry{
+		Time:      start,
+		ClientIP:  request.ClientIP(),
+		Method:    request.Method,
+		URI:       request.RequestURI,
+		Proto:     request.Proto,
+		Status:    statusCode,
+		Size:      w.size,
+		Duration:  time.Since(start),
+		Referer:   request.Referer(),
+		UserAgent: request.UserAgent(),
+		RequestID: request.ID(),
+		Route:     request.Param(":route"),
+	}
+}
+
+func formatCombined(e *AccessLogEntry) string {
+	return fmt.Sprintf(`%s - - [%s] "%s %s %s" %d %d %s %s %s %s %d`,
+		orDash(e.ClientIP), e.Time.Format("02/Jan/2006:15:04:05 -0700"), e.Method, e.URI, e.Proto,
+		e.Status, e.Size, strconv.Quote(orDash(e.Referer)), strconv.Quote(orDash(e.UserAgent)),
+		orDash(e.RequestID), orDash(e.Route), e.Duration.Microseconds())
+}
+
+func formatJSON(e *AccessLogEntry) string {
+	b, _ := json.Marshal(e)
+	return string(b)
+}
+
+func formatLogfmt(e *AccessLogEntry) string {
+	buf := new(strings.Builder)
+	pairs := []struct {
+		key   string
+		value string
+	}{
+		{"time", e.Time.Format(time.RFC3339)},
+		{"client_ip", e.ClientIP},
+		{"method", e.Method},
+		{"uri", e.URI},
+		{"proto", e.Proto},
+		{"status", strconv.Itoa(e.Status)},
+		{"size", strconv.FormatInt(e.Size, 10)},
+		{"duration", e.Duration.String()},
+		{"referer", e.Referer},
+		{"user_agent", e.UserAgent},
+		{"request_id", e.RequestID},
+		{"route", e.Route},
+	}
+	for i, p := range pairs {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(p.key)
+		buf.WriteByte('=')
+		if p.value == "" || strings.ContainsAny(p.value, " \"=") {
+			buf.WriteString(strconv.Quote(p.value))
+		} else {
+			buf.WriteString(p.value)
+		}
+	}
+	return buf.String()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
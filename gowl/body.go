@@ -0,0 +1,23 @@
+package gowl
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// limitedBody
+type limitedBody struct {
+	io.ReadCloser
+	exceeded bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	var e *http.MaxBytesError
+	if errors.As(err, &e) {
+		b.exceeded = true
+	}
+	return n, err
+}
@@ -4,22 +4,58 @@ import (
 	"encoding/json"
 	"fmt"
 	"html/template"
+	"io"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
+
+	"github.com/lokhman/gowl/types"
 )
 
 type Config struct {
-	Addr string `json:"addr"`
+	Addr  string   `json:"addr"`
+	Addrs []string `json:"addrs"`
 
 	EnableTLS bool   `json:"enable_tls"`
 	CertFile  string `json:"cert_file"`
 	KeyFile   string `json:"key_file"`
 
+	ClientCAFile       string         `json:"client_ca_file"`
+	ClientAuth         string         `json:"client_auth"`
+	MinTLSVersion      string         `json:"min_tls_version"`
+	CipherSuites       []string       `json:"cipher_suites"`
+	CertReloadInterval types.Duration `json:"cert_reload_interval"`
+	DevCertHosts       []string       `json:"dev_cert_hosts"`
+
+	EnableH2C                 bool `json:"enable_h2c"`
+	HTTP2MaxConcurrentStreams int  `json:"http2_max_concurrent_streams"`
+	HTTP2MaxFrameSize         int  `json:"http2_max_frame_size"`
+
 	ServerName string `json:"server_name"`
 
-	NotFoundHandler         Handler `json:"-"`
-	MethodNotAllowedHandler Handler `json:"-"`
+	RequestIDHeader string `json:"request_id_header"`
+
+	AccessLog           string    `json:"access_log"`
+	AccessLogFormat     string    `json:"access_log_format"`
+	AccessLogSampleRate float64   `json:"access_log_sample_rate"`
+	AccessLogWriter     io.Writer `json:"-"`
+
+	AllowedHosts   []string `json:"allowed_hosts"`
+	TrustedProxies []string `json:"trusted_proxies"`
+
+	ReadTimeout       types.Duration `json:"read_timeout"`
+	ReadHeaderTimeout types.Duration `json:"read_header_timeout"`
+	WriteTimeout      types.Duration `json:"write_timeout"`
+	IdleTimeout       types.Duration `json:"idle_timeout"`
+	MaxHeaderBytes    int            `json:"max_header_bytes"`
+	ShutdownTimeout   types.Duration `json:"shutdown_timeout"`
+	HandlerTimeout    types.Duration `json:"handler_timeout"`
+
+	NotFoundHandler         Handler      `json:"-"`
+	MethodNotAllowedHandler Handler      `json:"-"`
+	ErrorHandler            ErrorHandler `json:"-"`
 
 	HandleOptions          bool `json:"handle_options"`
 	HandleMethodNotAllowed bool `json:"handle_method_not_allowed"`
@@ -27,6 +63,12 @@ type Config struct {
 
 	RedirectUpperCasePath bool `json:"redirect_upper_case_path"`
 
+	StrictRouting bool `json:"strict_routing"`
+
+	StackTraceDepth int `json:"stack_trace_depth"`
+
+	SigningKey string `json:"signing_key"`
+
 	TemplatePath    string `json:"template_path"`
 	TemplateFileExt string `json:"template_file_ext"`
 
@@ -35,30 +77,117 @@ type Config struct {
 
 func (c *Config) String() string {
 	buf := new(strings.Builder)
-	fmt.Fprintf(buf, "Addr: %s\n", c.Addr)
+	fmt.Fprintf(buf, "Addr: %s\n", strings.Join(c.ListenAddrs(), ", "))
 	fmt.Fprintf(buf, "Enable TLS: %t\n", c.EnableTLS)
 	if c.EnableTLS {
 		fmt.Fprintf(buf, "Cert file: %s\n", c.CertFile)
 		fmt.Fprintf(buf, "Key file: %s\n", c.KeyFile)
+		if c.ClientCAFile != "" {
+			fmt.Fprintf(buf, "Client CA file: %s\n", c.ClientCAFile)
+		}
+		if c.ClientAuth != "" {
+			fmt.Fprintf(buf, "Client auth: %s\n", c.ClientAuth)
+		}
+		fmt.Fprintf(buf, "Min TLS version: %s\n", c.MinTLSVersion)
+		if len(c.CipherSuites) > 0 {
+			fmt.Fprintf(buf, "Cipher suites: %s\n", strings.Join(c.CipherSuites, ", "))
+		}
+		fmt.Fprintf(buf, "Cert reload interval: %s\n", c.CertReloadInterval)
+		if EnvMode() == Development {
+			fmt.Fprintf(buf, "Dev cert hosts: %s\n", strings.Join(c.DevCertHosts, ", "))
+		}
+	}
+	fmt.Fprintf(buf, "Protocols: %s\n", strings.Join(c.protocolNames(), ", "))
+	if c.EnableTLS || c.EnableH2C {
+		fmt.Fprintf(buf, "HTTP/2 max concurrent streams: %d\n", c.HTTP2MaxConcurrentStreams)
+		fmt.Fprintf(buf, "HTTP/2 max frame size: %d\n", c.HTTP2MaxFrameSize)
 	}
 	fmt.Fprintf(buf, "Server name: %s\n", c.ServerName)
+	fmt.Fprintf(buf, "Request ID header: %s\n", c.RequestIDHeader)
+	if c.AccessLog != "" || c.AccessLogWriter != nil {
+		fmt.Fprintf(buf, "Access log: %s\n", c.AccessLog)
+		fmt.Fprintf(buf, "Access log format: %s\n", c.AccessLogFormat)
+		fmt.Fprintf(buf, "Access log sample rate: %g\n", c.AccessLogSampleRate)
+	}
+	fmt.Fprintf(buf, "Allowed hosts: %s\n", strings.Join(c.AllowedHosts, ", "))
+	fmt.Fprintf(buf, "Trusted proxies: %s\n", strings.Join(c.TrustedProxies, ", "))
+	fmt.Fprintf(buf, "Read timeout: %s\n", c.ReadTimeout)
+	fmt.Fprintf(buf, "Read header timeout: %s\n", c.ReadHeaderTimeout)
+	fmt.Fprintf(buf, "Write timeout: %s\n", c.WriteTimeout)
+	fmt.Fprintf(buf, "Idle timeout: %s\n", c.IdleTimeout)
+	fmt.Fprintf(buf, "Max header bytes: %d\n", c.MaxHeaderBytes)
+	fmt.Fprintf(buf, "Shutdown timeout: %s\n", c.ShutdownTimeout)
+	fmt.Fprintf(buf, "Handler timeout: %s\n", c.HandlerTimeout)
 	fmt.Fprintf(buf, "Handle OPTIONS: %t\n", c.HandleOptions)
 	fmt.Fprintf(buf, "Handle method not allowed: %t\n", c.HandleMethodNotAllowed)
 	fmt.Fprintf(buf, "Redirect trailing slash: %t\n", c.RedirectTrailingSlash)
 	fmt.Fprintf(buf, "Redirect upper case path: %t\n", c.RedirectUpperCasePath)
+	fmt.Fprintf(buf, "Strict routing: %t\n", c.StrictRouting)
+	fmt.Fprintf(buf, "Stack trace depth: %d\n", c.StackTraceDepth)
 	fmt.Fprintf(buf, "Template path: %s\n", c.TemplatePath)
 	fmt.Fprintf(buf, "Template file extension: %s\n", c.TemplateFileExt)
 	return buf.String()
 }
 
+// ListenAddrs returns all addresses the server listens on. Address can be
+// "host:port", "unix:/path/to/socket" or "systemd:name" for a socket passed
+// by systemd, where name is either LISTEN_FDNAMES entry or index.
+// DefaultAddr is used only if no address is configured.
+func (c *Config) ListenAddrs() []string {
+	var addrs []string
+	if c.Addr != "" {
+		addrs = append(addrs, c.Addr)
+	}
+	addrs = append(addrs, c.Addrs...)
+	if len(addrs) == 0 {
+		return []string{DefaultAddr}
+	}
+	return addrs
+}
+
+func (c *Config) protocols() *http.Protocols {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	if c.EnableTLS {
+		protocols.SetHTTP2(true)
+	} else if c.EnableH2C {
+		protocols.SetUnencryptedHTTP2(true)
+	}
+	return protocols
+}
+
+func (c *Config) protocolNames() []string {
+	protocols := c.protocols()
+	names := []string{"HTTP/1.1"}
+	if protocols.HTTP2() {
+		names = append(names, "HTTP/2")
+	}
+	if protocols.UnencryptedHTTP2() {
+		names = append(names, "HTTP/2 cleartext (h2c)")
+	}
+	return names
+}
+
 func NewConfig() *Config {
 	return &Config{
-		Addr:                   ":8000",
 		ServerName:             ServerName,
+		RequestIDHeader:        "X-Request-ID",
+		AccessLogFormat:        AccessLogCombined,
+		AccessLogSampleRate:    1,
+		MinTLSVersion:          "1.2",
+		CertReloadInterval:     types.Duration(time.Minute),
+		DevCertHosts:           []string{"localhost", "127.0.0.1", "::1"},
+		ReadTimeout:            types.Duration(30 * time.Second),
+		ReadHeaderTimeout:      types.Duration(10 * time.Second),
+		WriteTimeout:           types.Duration(60 * time.Second),
+		IdleTimeout:            types.Duration(120 * time.Second),
+		MaxHeaderBytes:         http.DefaultMaxHeaderBytes,
+		ShutdownTimeout:        types.Duration(10 * time.Second),
 		HandleOptions:          true,
 		HandleMethodNotAllowed: true,
 		RedirectTrailingSlash:  true,
 		RedirectUpperCasePath:  true,
+		StackTraceDepth:        32,
 		TemplatePath:           filepath.Join(execPath, "templates"),
 		TemplateFileExt:        ".html",
 	}
@@ -1,36 +1,135 @@
 package gowl
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"os/signal"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/lokhman/gowl/console"
 	"golang.org/x/sync/errgroup"
 )
 
 func runCommand(out console.OutputInterface) {
-	var stack errgroup.Group
+	servers := make(map[string]ServerInterface)
 	if addr := *_server; addr != "" {
-		out.Printf("Starting server... %s\n", addr)
-		stack.Go(getServer(addr).Listen)
+		servers[addr] = getServer(addr)
 	} else {
-		count := 0
 		kernel.servers.Range(func(addr, server interface{}) bool {
-			out.Printf("Starting server... %s\n", addr.(string))
-			stack.Go(server.(ServerInterface).Listen)
-			count++
+			servers[addr.(string)] = server.(ServerInterface)
 			return true
 		})
-		if count == 0 {
+		if len(servers) == 0 {
 			out.Errorln("No registered servers")
 			return
 		}
 	}
+
+	// generate missing development certificates
+	if EnvMode() == Development {
+		for addr, server := range servers {
+			config := server.Config()
+			if !config.EnableTLS || (fileExists(config.CertFile) && fileExists(config.KeyFile)) {
+				continue
+			}
+			out.Printf("Generating development certificate... %s\n", addr)
+			if err := generateDevCertificate(config); err != nil {
+				Error.Print(err)
+				os.Exit(1)
+			}
+		}
+	}
+
+	// notify parent process once all servers are listening
+	started := new(sync.WaitGroup)
+	started.Add(len(servers))
+	go func() {
+		started.Wait()
+		notifyReady()
+	}()
+
+	stack, ctx := errgroup.WithContext(context.Background())
+	for addr, server := range servers {
+		out.Printf("Starting server... %s\n", addr)
+		server.On(EventStarted, func(_ EventInterface) { started.Done() })
+		stack.Go(server.Listen)
+	}
+
+	// wait for termination signal or server failure
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, append(append([]os.Signal{syscall.SIGINT, syscall.SIGTERM}, restartSignals...), reloadSignals...)...)
+wait:
+	for {
+		select {
+		case sig := <-signals:
+			if isSignal(sig, reloadSignals) {
+				out.Printf("Received %s, reloading certificates...\n", sig)
+				for addr, server := range servers {
+					if err := server.ReloadCertificate(); err != nil {
+						Error.Printf(`server "%s": %s`, addr, err)
+					}
+				}
+				continue
+			}
+			if !isSignal(sig, restartSignals) {
+				out.Printf("Received %s, shutting down...\n", sig)
+				break wait
+			}
+			out.Printf("Received %s, restarting...\n", sig)
+			if err := restart(servers); err != nil {
+				Error.Print(err)
+				continue
+			}
+			out.Println("New process is ready, shutting down...")
+			break wait
+		case <-ctx.Done():
+			break wait
+		}
+	}
+	signal.Stop(signals)
+
+	var shutdown errgroup.Group
+	for addr, server := range servers {
+		addr, server := addr, server
+		shutdown.Go(func() error {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Duration(server.Config().ShutdownTimeout))
+			defer cancel()
+
+			out.Printf("Stopping server... %s\n", addr)
+			if err := server.Shutdown(ctx); err != nil {
+				return fmt.Errorf(`server "%s" was not gracefully stopped: %s`, addr, err)
+			}
+			return nil
+		})
+	}
+
+	code := 0
 	if err := stack.Wait(); err != nil {
-		Error.Fatal(err)
+		Error.Print(err)
+		code = 1
+	}
+	if err := shutdown.Wait(); err != nil {
+		Error.Print(err)
+		code = 1
+	}
+	if code != 0 {
+		os.Exit(code)
 	}
 }
 
+func isSignal(sig os.Signal, signals []os.Signal) bool {
+	for _, s := range signals {
+		if sig == s {
+			return true
+		}
+	}
+	return false
+}
+
 func infoCommand(out console.OutputInterface) {
 	if addr := *_server; addr != "" {
 		out.Println(getServer(addr))
@@ -50,3 +149,64 @@ func infoCommand(out console.OutputInterface) {
 		return true
 	})
 }
+
+func routesLintCommand(out console.OutputInterface) {
+	errors, warnings := 0, 0
+	lint := func(addr string, server ServerInterface) {
+		for _, issue := range server.LintRoutes() {
+			out.Printf("%s %s\n", addr, issue)
+			if issue.Error {
+				errors++
+			} else {
+				warnings++
+			}
+		}
+	}
+
+	if addr := *_server; addr != "" {
+		lint(addr, getServer(addr))
+	} else {
+		kernel.servers.Range(func(addr, server interface{}) bool {
+			lint(addr.(string), server.(ServerInterface))
+			return true
+		})
+	}
+
+	out.Printf("%d error(s), %d warning(s)\n", errors, warnings)
+	if errors > 0 {
+		os.Exit(1)
+	}
+}
+
+func tlsDevCertCommand(out console.OutputInterface) {
+	count := 0
+	generate := func(addr string, server ServerInterface) {
+		config := server.Config()
+		if !config.EnableTLS {
+			return
+		}
+		if err := generateDevCertificate(config); err != nil {
+			fatal(`Server "%s": %s`, addr, err)
+		}
+		out.Printf("%s %s %s\n", addr, config.CertFile, config.KeyFile)
+		count++
+	}
+
+	if addr := *_server; addr != "" {
+		generate(addr, getServer(addr))
+	} else {
+		kernel.servers.Range(func(addr, server interface{}) bool {
+			generate(addr.(string), server.(ServerInterface))
+			return true
+		})
+	}
+
+	if count == 0 {
+		out.Errorln("No servers with TLS enabled")
+	}
+}
+
+func fileExists(filename string) bool {
+	_, err := os.Stat(filename)
+	return err == nil
+}
@@ -0,0 +1,89 @@
+package gowl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DateConverterLayout = "2006-01-02"
+
+// ConverterInterface
+type ConverterInterface interface {
+	Requirement() string
+	Parse(value string) (interface{}, error)
+	Format(value interface{}) (string, error)
+}
+
+// converter
+type converter struct {
+	requirement string
+	parse       func(value string) (interface{}, error)
+	format      func(value interface{}) (string, error)
+}
+
+func (c *converter) Requirement() string {
+	return c.requirement
+}
+
+func (c *converter) Parse(value string) (interface{}, error) {
+	return c.parse(value)
+}
+
+func (c *converter) Format(value interface{}) (string, error) {
+	return c.format(value)
+}
+
+func NewConverter(requirement string, parse func(value string) (interface{}, error), format func(value interface{}) (string, error)) ConverterInterface {
+	return &converter{requirement, parse, format}
+}
+
+// ...
+func parseInt(value string) (interface{}, error) {
+	return strconv.Atoi(value)
+}
+
+func formatInt(value interface{}) (string, error) {
+	switch value.(type) {
+	case int, uint, int8, int16, int32, int64, uint8, uint16, uint32, uint64:
+		return fmt.Sprintf("%d", value), nil
+	}
+	return "", fmt.Errorf("gowl: cannot format %T as int", value)
+}
+
+func parseUUID(value string) (interface{}, error) {
+	return strings.ToLower(value), nil
+}
+
+func formatUUID(value interface{}) (string, error) {
+	if v, ok := value.([16]byte); ok {
+		return fmt.Sprintf("%x-%x-%x-%x-%x", v[0:4], v[4:6], v[6:8], v[8:10], v[10:16]), nil
+	}
+	return formatString(value)
+}
+
+func parseString(value string) (interface{}, error) {
+	return value, nil
+}
+
+func formatString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case fmt.Stringer:
+		return v.String(), nil
+	}
+	return "", fmt.Errorf("gowl: cannot format %T as string", value)
+}
+
+func parseDate(value string) (interface{}, error) {
+	return time.Parse(DateConverterLayout, value)
+}
+
+func formatDate(value interface{}) (string, error) {
+	if v, ok := value.(time.Time); ok {
+		return v.Format(DateConverterLayout), nil
+	}
+	return "", fmt.Errorf("gowl: cannot format %T as date", value)
+}
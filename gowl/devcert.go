@@ -0,0 +1,156 @@
+package gowl
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	devCAFile    = "gowl-dev-ca.pem"
+	devCAKeyFile = "gowl-dev-ca.key"
+)
+
+// generateDevCertificate creates a leaf certificate for the configured hosts
+// in CertFile and KeyFile signed by a local CA, which is stored next to the
+// certificate and reused so it has to be trusted only once.
+func generateDevCertificate(config *Config) error {
+	if config.CertFile == "" || config.KeyFile == "" {
+		return errors.New("gowl: certificate and key files are not configured")
+	}
+
+	dir := filepath.Dir(config.CertFile)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.Wrap(err, "gowl: cannot create certificate directory")
+	}
+
+	ca, caKey, err := loadDevCA(filepath.Join(dir, devCAFile), filepath.Join(dir, devCAKeyFile))
+	if err != nil {
+		return err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return errors.Wrap(err, "gowl: cannot generate key")
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: newSerialNumber(),
+		Subject:      pkix.Name{Organization: []string{"gowl development certificate"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(1, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, host := range config.DevCertHosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+	if len(template.DNSNames) > 0 {
+		template.Subject.CommonName = template.DNSNames[0]
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, key.Public(), caKey)
+	if err != nil {
+		return errors.Wrap(err, "gowl: cannot create certificate")
+	}
+	if err = writePEM(config.CertFile, "CERTIFICATE", der, 0644); err != nil {
+		return err
+	}
+	return writePrivateKey(config.KeyFile, key)
+}
+
+func loadDevCA(certFile, keyFile string) (*x509.Certificate, crypto.Signer, error) {
+	certPEM, certErr := ioutil.ReadFile(certFile)
+	keyPEM, keyErr := ioutil.ReadFile(keyFile)
+	if certErr == nil && keyErr == nil {
+		certBlock, _ := pem.Decode(certPEM)
+		keyBlock, _ := pem.Decode(keyPEM)
+		if certBlock == nil || keyBlock == nil {
+			return nil, nil, errors.New("gowl: cannot decode development CA")
+		}
+		cert, err := x509.ParseCertificate(certBlock.Bytes)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "gowl: cannot parse development CA")
+		}
+		key, err := x509.ParsePKCS8PrivateKey(keyBlock.Bytes)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "gowl: cannot parse development CA key")
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, nil, errors.New("gowl: development CA key cannot sign")
+		}
+		return cert, signer, nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "gowl: cannot generate key")
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          newSerialNumber(),
+		Subject:               pkix.Name{CommonName: "gowl development CA", Organization: []string{"gowl development CA"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(10, 0, 0),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "gowl: cannot create development CA")
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "gowl: cannot parse development CA")
+	}
+	if err = writePEM(certFile, "CERTIFICATE", der, 0644); err != nil {
+		return nil, nil, err
+	}
+	if err = writePrivateKey(keyFile, key); err != nil {
+		return nil, nil, err
+	}
+	return cert, key, nil
+}
+
+func writePrivateKey(filename string, key crypto.PrivateKey) error {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return errors.Wrap(err, "gowl: cannot encode key")
+	}
+	return writePEM(filename, "PRIVATE KEY", der, 0600)
+}
+
+func writePEM(filename, blockType string, der []byte, perm os.FileMode) error {
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := ioutil.WriteFile(filename, data, perm); err != nil {
+		return errors.Wrapf(err, `gowl: cannot write "%s"`, filename)
+	}
+	return nil
+}
+
+func newSerialNumber() *big.Int {
+	n, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
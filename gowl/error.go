@@ -5,38 +5,165 @@ import (
 	"html/template"
 	"io"
 	"net/http"
-	"runtime"
-	"strings"
+	"strconv"
 
+	"github.com/lokhman/gowl/httputil"
 	"github.com/lokhman/gowl/types"
 	"github.com/pkg/errors"
 )
 
-type stackTracer interface {
-	StackTrace() errors.StackTrace
+const (
+	MIMEHTML        = "text/html"
+	MIMEJSON        = "application/json"
+	MIMEProblemJSON = "application/problem+json"
+)
+
+var errorOffers = []string{MIMEHTML, MIMEJSON, MIMEProblemJSON}
+
+// HTTPError
+type HTTPError struct {
+	StatusCode int
+	Message    string // public message shown to the client
+	Err        error
+}
+
+func (e *HTTPError) Error() string {
+	message := e.Message
+	if message == "" {
+		message = http.StatusText(e.StatusCode)
+	}
+	if e.Err != nil {
+		return message + ": " + e.Err.Error()
+	}
+	return message
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+func NewHTTPError(statusCode int, message string, err error) *HTTPError {
+	return &HTTPError{StatusCode: statusCode, Message: message, Err: err}
+}
+
+// ErrorHandler
+type ErrorHandler func(r *Request, statusCode int, err error) ResponseInterface
+
+// DefaultErrorHandler renders error as HTML, JSON or problem details
+// depending on Accept header of the request.
+func DefaultErrorHandler(r *Request, statusCode int, err error) ResponseInterface {
+	switch httputil.NegotiateAcceptHeader(r.Header, "Accept", errorOffers) {
+	case MIMEJSON:
+		return JSONErrorHandler(r, statusCode, err)
+	case MIMEProblemJSON:
+		return ProblemErrorHandler(r, statusCode, err)
+	}
+	return HTMLErrorHandler(r, statusCode, err)
+}
+
+// HTMLErrorHandler renders "errors/<status>" or "errors/error" template if
+// loaded, or falls back to ErrorTemplate.
+func HTMLErrorHandler(r *Request, statusCode int, err error) ResponseInterface {
+	debug, requestID := getErrorDebug(r, err)
+	message := getErrorMessage(err)
+	if t := getErrorTemplate(r, statusCode); t != nil {
+		response := TemplateResponse(statusCode, t, types.Data{
+			"status":     statusCode,
+			"title":      http.StatusText(statusCode),
+			"message":    message,
+			"debug":      debug,
+			"request_id": requestID,
+			"request":    r,
+		})
+		response.Header().Set("Content-Type", "text/html; charset=utf-8")
+		return response
+	}
+	return errorResponse(statusCode, message, debug, requestID)
+}
+
+// JSONErrorHandler
+func JSONErrorHandler(r *Request, statusCode int, err error) ResponseInterface {
+	content := types.Data{
+		"status":  statusCode,
+		"message": http.StatusText(statusCode),
+	}
+	if message := getErrorMessage(err); message != "" {
+		content["message"] = message
+	}
+	debug, requestID := getErrorDebug(r, err)
+	if debug != "" {
+		content["debug"] = debug
+	}
+	if requestID != "" {
+		content["request_id"] = requestID
+	}
+	return JSONResponse(statusCode, types.Data{"error": content})
+}
+
+// ProblemErrorHandler renders RFC 7807 problem details.
+func ProblemErrorHandler(r *Request, statusCode int, err error) ResponseInterface {
+	content := types.Data{
+		"type":     "about:blank",
+		"title":    http.StatusText(statusCode),
+		"status":   statusCode,
+		"instance": r.URL.Path,
+	}
+	if message := getErrorMessage(err); message != "" {
+		content["detail"] = message
+	}
+	debug, requestID := getErrorDebug(r, err)
+	if debug != "" {
+		content["debug"] = debug
+	}
+	if requestID != "" {
+		content["request_id"] = requestID
+	}
+	response := JSONResponse(statusCode, content)
+	response.Header().Set("Content-Type", MIMEProblemJSON+"; charset=utf-8")
+	return response
+}
+
+func getErrorMessage(err error) string {
+	var e *HTTPError
+	if errors.As(err, &e) {
+		return e.Message
+	}
+	return ""
 }
 
-func getMainStackTrace(stack errors.StackTrace) errors.StackTrace {
-	start := 0
-	for i, frame := range stack {
-		fn := runtime.FuncForPC(uintptr(frame) - 1)
-		if fn == nil {
-			continue
-		}
-		if strings.HasPrefix(fn.Name(), "main.") {
-			start = i
-			break
-		}
+func getErrorDebug(r *Request, err error) (debug, requestID string) {
+	if !*_debug {
+		return
+	}
+	if err != nil {
+		debug = err.Error()
 	}
-	return stack[start:]
+	return debug, r.ID()
+}
+
+func getErrorTemplate(r *Request, statusCode int) *template.Template {
+	if r.server == nil {
+		return nil
+	}
+	ext := r.server.config.TemplateFileExt
+	if t := r.server.templates["errors/"+strconv.Itoa(statusCode)+ext]; t != nil {
+		return t
+	}
+	return r.server.templates["errors/error"+ext]
 }
 
 func ErrorResponse(statusCode int, debug string) ResponseInterface {
+	return errorResponse(statusCode, "", debug, "")
+}
+
+func errorResponse(statusCode int, message, debug, requestID string) ResponseInterface {
 	response := NewResponse(statusCode, func(w io.Writer) error {
 		return ErrorTemplate.Execute(w, types.StringMap{
-			"name":   fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
-			"server": ServerName,
-			"debug":  debug,
+			"name":       fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+			"server":     ServerName,
+			"message":    message,
+			"debug":      debug,
+			"request_id": requestID,
 		})
 	})
 	response.header.Set("Content-Type", "text/html; charset=utf-8")
@@ -52,9 +179,15 @@ var ErrorTemplate = template.Must(template.New("error").Parse(`<!DOCTYPE html>
 </head>
 <body style="font-family: sans-serif">
     <h1 style="font-size: x-large">{{.name}}</h1>
+	{{if .message -}}
+		<p>{{.message}}</p>
+	{{- end}}
 	{{if .debug -}}
 		<pre>{{.debug}}</pre>
 	{{- end}}
+	{{if .request_id -}}
+		<p><small>Request ID: {{.request_id}}</small></p>
+	{{- end}}
     <hr style="border-style: outset">
 	<small>{{.server}}</small>
 </body>
@@ -1,17 +1,36 @@
 package gowl
 
 import (
+	"runtime"
+
 	"github.com/lokhman/gowl/events"
 )
 
 type EventInterface = events.EventInterface
 
 const (
+	EventStarting events.EventType = "starting"
+	EventStarted  events.EventType = "started"
+	EventStopping events.EventType = "stopping"
+	EventStopped  events.EventType = "stopped"
+
 	EventRequest  events.EventType = "request"
 	EventResponse events.EventType = "response"
 	EventPanic    events.EventType = "panic"
+	EventError    events.EventType = "error"
 )
 
+// ServerEvent
+type ServerEvent struct {
+	events.Event
+
+	server ServerInterface
+}
+
+func (e *ServerEvent) Server() ServerInterface {
+	return e.server
+}
+
 // RequestEvent
 type RequestEvent struct {
 	events.Event
@@ -52,9 +71,51 @@ func (e *ResponseEvent) SetResponse(response ResponseInterface) {
 type PanicEvent struct {
 	events.Event
 
-	error error
+	request *Request
+	value   interface{}
+	error   error
+	stack   []runtime.Frame
+}
+
+func (e *PanicEvent) Request() *Request {
+	return e.request
+}
+
+// Value returns the value passed to panic.
+func (e *PanicEvent) Value() interface{} {
+	return e.value
 }
 
 func (e *PanicEvent) Error() error {
 	return e.error
 }
+
+// Stack returns frames starting from the panic site.
+func (e *PanicEvent) Stack() []runtime.Frame {
+	return e.stack
+}
+
+func (e *PanicEvent) RequestID() string {
+	return e.request.ID()
+}
+
+// ErrorEvent
+type ErrorEvent struct {
+	events.Event
+
+	request  *Request
+	error    error
+	response ResponseInterface
+}
+
+func (e *ErrorEvent) Request() *Request {
+	return e.request
+}
+
+func (e *ErrorEvent) Error() error {
+	return e.error
+}
+
+func (e *ErrorEvent) SetResponse(response ResponseInterface) {
+	e.response = response
+}
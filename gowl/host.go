@@ -0,0 +1,42 @@
+package gowl
+
+import (
+	"strings"
+
+	"github.com/lokhman/gowl/httputil"
+)
+
+// hosts that are always allowed in development mode
+var devAllowedHosts = []string{"localhost", "*.localhost", "127.0.0.1", "::1"}
+
+// isAllowedHost checks host against AllowedHosts, where "*.example.com"
+// matches any subdomain of example.com and "*" matches any host.
+func (s *server) isAllowedHost(host string) bool {
+	if len(s.config.AllowedHosts) == 0 {
+		return true
+	}
+
+	host = strings.ToLower(strings.TrimSuffix(httputil.StripPort(host), "."))
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if host == "" {
+		return false
+	}
+
+	if EnvMode() == Development && matchHostPatterns(host, devAllowedHosts) {
+		return true
+	}
+	return matchHostPatterns(host, s.config.AllowedHosts)
+}
+
+func matchHostPatterns(host string, patterns []string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		switch {
+		case pattern == "*" || pattern == host:
+			return true
+		case strings.HasPrefix(pattern, "*.") && strings.HasSuffix(host, pattern[1:]):
+			return true
+		}
+	}
+	return false
+}
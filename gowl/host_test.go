@@ -0,0 +1,71 @@
+package gowl
+
+import "testing"
+
+func TestIsAllowedHost(t *testing.T) {
+	config := NewConfig()
+	config.AllowedHosts = []string{"*.example.com", "EXAMPLE.org", "192.0.2.1", "2001:db8::1"}
+	s := NewServer(config).(*server)
+
+	tests := []struct {
+		host    string
+		allowed bool
+	}{
+		{"www.example.com", true},
+		{"a.b.example.com", true},
+		{"WWW.Example.COM", true},
+		{"www.example.com:8080", true},
+		{"www.example.com.", true},
+		{"www.example.com.:8080", true},
+		{"example.com", false}, // apex is not a subdomain
+		{"badexample.com", false},
+		{"example.com.evil.com", false},
+		{"example.org", true},
+		{"example.org:443", true},
+		{"www.example.org", false},
+		{"192.0.2.1:80", true},
+		{"[2001:db8::1]:80", true},
+		{"[2001:db8::1]", true},
+		{"localhost", false}, // allowed only in development mode
+		{"", false},
+		{":80", false},
+	}
+	for _, tt := range tests {
+		if allowed := s.isAllowedHost(tt.host); allowed != tt.allowed {
+			t.Errorf("isAllowedHost(%q) = %t, want %t", tt.host, allowed, tt.allowed)
+		}
+	}
+}
+
+func TestIsAllowedHostAny(t *testing.T) {
+	s := NewServer(NewConfig()).(*server)
+	if !s.isAllowedHost("anything.test") {
+		t.Error("any host must be allowed without AllowedHosts")
+	}
+
+	s.config.AllowedHosts = []string{"*"}
+	if !s.isAllowedHost("anything.test:8080") {
+		t.Error(`any host must be allowed with "*"`)
+	}
+}
+
+func TestMatchHostPatterns(t *testing.T) {
+	tests := []struct {
+		host     string
+		patterns []string
+		match    bool
+	}{
+		{"example.com", []string{"example.com"}, true},
+		{"example.com", []string{"*.example.com"}, false},
+		{"www.example.com", []string{"*.example.com"}, true},
+		{"wwwexample.com", []string{"*.example.com"}, false},
+		{"www.example.com", []string{"*.Example.com"}, true},
+		{"www.example.com", []string{"example.com", "*"}, true},
+		{"www.example.com", nil, false},
+	}
+	for _, tt := range tests {
+		if match := matchHostPatterns(tt.host, tt.patterns); match != tt.match {
+			t.Errorf("matchHostPatterns(%q, %q) = %t, want %t", tt.host, tt.patterns, match, tt.match)
+		}
+	}
+}
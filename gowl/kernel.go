@@ -16,7 +16,8 @@ import (
 )
 
 const (
-	ServerName = "gowl/1.0"
+	ServerName  = "gowl/1.0"
+	DefaultAddr = ":8000"
 )
 
 const (
@@ -26,8 +27,9 @@ const (
 )
 
 var kernel struct {
-	servers  sync.Map
-	commands sync.Map
+	servers    sync.Map
+	commands   sync.Map
+	converters sync.Map
 }
 
 var execPath string
@@ -74,6 +76,13 @@ func init() {
 
 	RegisterCommand(console.NewCommand("run", "run registered servers", runCommand))
 	RegisterCommand(console.NewCommand("info", "display information about registered servers", infoCommand))
+	RegisterCommand(console.NewCommand("routes:lint", "detect shadowed and overlapping routes", routesLintCommand))
+	RegisterCommand(console.NewCommand("tls:dev-cert", "generate self-signed development certificates", tlsDevCertCommand))
+
+	RegisterConverter("int", NewConverter(`-?[0-9]+`, parseInt, formatInt))
+	RegisterConverter("uuid", NewConverter(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`, parseUUID, formatUUID))
+	RegisterConverter("slug", NewConverter(`[a-z0-9]+(?:-[a-z0-9]+)*`, parseString, formatString))
+	RegisterConverter("date", NewConverter(`[0-9]{4}-[0-9]{2}-[0-9]{2}`, parseDate, formatDate))
 }
 
 func ExecPath() string {
@@ -96,12 +105,21 @@ func RegisterCommand(command console.CommandInterface) {
 	kernel.commands.Store(name, command)
 }
 
+func RegisterConverter(name string, converter ConverterInterface) {
+	if _, ok := kernel.converters.Load(name); ok {
+		panic(fmt.Sprintf(`gowl: converter "%s" is already registered`, name))
+	}
+	kernel.converters.Store(name, converter)
+}
+
 func RegisterServer(server ServerInterface) {
-	addr := server.Config().Addr
-	if _, ok := kernel.servers.Load(addr); ok {
-		panic(fmt.Sprintf(`gowl: server with address "%s" is already registered`, addr))
+	addrs := server.Config().ListenAddrs()
+	for _, addr := range addrs {
+		if findServer(addr) != nil {
+			panic(fmt.Sprintf(`gowl: server with address "%s" is already registered`, addr))
+		}
 	}
-	kernel.servers.Store(addr, server)
+	kernel.servers.Store(addrs[0], server)
 }
 
 func Run(server ...ServerInterface) {
@@ -124,12 +142,28 @@ func Run(server ...ServerInterface) {
 	flag.Usage()
 }
 
+func findServer(addr string) (found ServerInterface) {
+	if server, ok := kernel.servers.Load(addr); ok {
+		return server.(ServerInterface)
+	}
+	kernel.servers.Range(func(_, server interface{}) bool {
+		for _, a := range server.(ServerInterface).Config().ListenAddrs() {
+			if a == addr {
+				found = server.(ServerInterface)
+				return false
+			}
+		}
+		return true
+	})
+	return
+}
+
 func getServer(addr string) ServerInterface {
-	server, ok := kernel.servers.Load(addr)
-	if !ok {
+	server := findServer(addr)
+	if server == nil {
 		fatal(`Server "%s" is not registered`, addr)
 	}
-	return server.(ServerInterface)
+	return server
 }
 
 func getCommand(name string) console.CommandInterface {
@@ -140,6 +174,14 @@ func getCommand(name string) console.CommandInterface {
 	return command.(console.CommandInterface)
 }
 
+func getConverter(name string) ConverterInterface {
+	converter, ok := kernel.converters.Load(name)
+	if !ok {
+		return nil
+	}
+	return converter.(ConverterInterface)
+}
+
 func fatal(format string, a ...interface{}) {
 	fmt.Fprintln(stderr, fmt.Sprintf(format, a...))
 	os.Exit(1)
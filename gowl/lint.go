@@ -0,0 +1,311 @@
+package gowl
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lokhman/gowl/helpers"
+)
+
+// RouteIssue
+type RouteIssue struct {
+	Route   string
+	Other   string
+	Error   bool
+	Message string
+}
+
+func (i RouteIssue) String() string {
+	level := "warning"
+	if i.Error {
+		level = "error"
+	}
+	return fmt.Sprintf("%s: %s", level, i.Message)
+}
+
+// lintSegment
+type lintSegment struct {
+	static string
+	re     *regexp.Regexp // nil for static segment
+	any    bool           // matches any non-empty segment
+	empty  bool           // may match empty segment
+}
+
+func (s lintSegment) covers(o lintSegment) bool {
+	switch {
+	case s.re == nil:
+		return o.re == nil && s.static == o.static
+	case o.re == nil:
+		return s.re.MatchString(o.static)
+	case s.any:
+		return !o.empty
+	}
+	return s.re.String() == o.re.String()
+}
+
+func (s lintSegment) overlaps(o lintSegment) bool {
+	switch {
+	case s.re == nil && o.re == nil:
+		return s.static == o.static
+	case s.re == nil:
+		return o.re.MatchString(s.static)
+	case o.re == nil:
+		return s.re.MatchString(o.static)
+	}
+	return true // assume dynamic segments overlap
+}
+
+// lintRoute
+type lintRoute struct {
+	route    *route
+	segments []lintSegment
+	labels   []lintSegment // nil if route has no host or it cannot be analysed by labels
+	catchAll bool
+	prefix   bool // prefix served by mounted handler
+}
+
+func (r *lintRoute) coversPath(o *lintRoute) bool {
+	n := len(r.segments)
+	if r.catchAll {
+		if !r.segments[n-1].any || len(o.segments) < n {
+			return false
+		}
+		n--
+	} else if o.catchAll || len(o.segments) != n {
+		return false
+	}
+	for i := 0; i < n; i++ {
+		if !r.segments[i].covers(o.segments[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *lintRoute) overlapsPath(o *lintRoute) bool {
+	n, m := len(r.segments), len(o.segments)
+	switch {
+	case r.catchAll && o.catchAll:
+		if m < n {
+			n = m
+		}
+		n--
+	case r.catchAll:
+		if m < n {
+			return false
+		}
+		n--
+	case o.catchAll:
+		if n < m {
+			return false
+		}
+		n = m - 1
+	case n != m:
+		return false
+	}
+	for i := 0; i < n; i++ {
+		if !r.segments[i].overlaps(o.segments[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *lintRoute) coversMethods(o *lintRoute) bool {
+	if len(r.route.methods) == 0 {
+		return true
+	}
+	if len(o.route.methods) == 0 {
+		return false
+	}
+	for _, method := range o.route.methods {
+		if helpers.IndexString(method, r.route.methods) == -1 {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *lintRoute) overlapsMethods(o *lintRoute) bool {
+	if len(r.route.methods) == 0 || len(o.route.methods) == 0 {
+		return true
+	}
+	for _, method := range o.route.methods {
+		if helpers.IndexString(method, r.route.methods) != -1 {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *lintRoute) coversHost(o *lintRoute) bool {
+	switch {
+	case r.route.host == "":
+		return true
+	case r.labels == nil && isStaticHost(o.route.host):
+		return r.route.reHost.MatchString(strings.ToLower(o.route.host))
+	case r.labels == nil || o.labels == nil:
+		return r.route.host == o.route.host
+	case len(r.labels) != len(o.labels):
+		return false
+	}
+	for i := range r.labels {
+		if !r.labels[i].covers(o.labels[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *lintRoute) overlapsHost(o *lintRoute) bool {
+	switch {
+	case r.route.host == "" || o.route.host == "":
+		return true
+	case r.labels == nil && isStaticHost(o.route.host):
+		return r.route.reHost.MatchString(strings.ToLower(o.route.host))
+	case o.labels == nil && isStaticHost(r.route.host):
+		return o.route.reHost.MatchString(strings.ToLower(r.route.host))
+	case r.labels == nil || o.labels == nil:
+		return true // assume parameters spanning labels overlap
+	case len(r.labels) != len(o.labels):
+		return false
+	}
+	for i := range r.labels {
+		if !r.labels[i].overlaps(o.labels[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isStaticHost(host string) bool {
+	return strings.IndexByte(host, '{') == -1
+}
+
+// newLintLabels splits host of the route into labels, which are compared
+// the same way as path segments.
+func newLintLabels(route *route) []lintSegment {
+	var labels []lintSegment
+	for _, label := range strings.Split(route.host, ".") {
+		m := reRoutePathParams.FindAllStringSubmatchIndex(label, -1)
+		if m == nil {
+			labels = append(labels, lintSegment{static: strings.ToLower(label)})
+			continue
+		}
+
+		lastIndex, expr := 0, "^"
+		for _, v := range m {
+			attr := route.params[label[v[2]:v[3]]]
+			if helpers.RegexpCanMatchRune(attr.Requirement, '.') {
+				return nil // cannot be analysed by labels
+			}
+			expr += regexp.QuoteMeta(label[lastIndex:v[0]]) + "(?:" + attr.Requirement + ")"
+			lastIndex = v[1]
+		}
+		expr += regexp.QuoteMeta(label[lastIndex:]) + "$"
+
+		re := regexp.MustCompile("(?i)" + expr)
+		labels = append(labels, lintSegment{
+			re:    re,
+			any:   expr == "^(?:"+RouteHostParamRequirement+")$",
+			empty: re.MatchString(""),
+		})
+	}
+	return labels
+}
+
+func newLintRoute(route *route) *lintRoute {
+	r := &lintRoute{route: route}
+	if route.host != "" {
+		r.labels = newLintLabels(route)
+	}
+	for _, segment := range strings.Split(route.path[1:], "/") {
+		m := reRoutePathParams.FindAllStringSubmatchIndex(segment, -1)
+		if m == nil {
+			r.segments = append(r.segments, lintSegment{static: segment})
+			continue
+		}
+
+		if len(m) == 1 && m[0][4] != -1 {
+			attr := route.params[segment[m[0][2]:m[0][3]]]
+			r.segments = append(r.segments, lintSegment{
+				re:    attr.reRequirement,
+				any:   attr.Requirement == RouteCatchAllRequirement,
+				empty: attr.reRequirement.MatchString(""),
+			})
+			r.catchAll = true
+			break
+		}
+
+		lastIndex, expr := 0, "^"
+		for _, v := range m {
+			attr := route.params[segment[v[2]:v[3]]]
+			if attr.spanSegments {
+				return nil // cannot be analysed by segments
+			}
+			expr += regexp.QuoteMeta(segment[lastIndex:v[0]]) + "(?:" + attr.Requirement + ")"
+			lastIndex = v[1]
+		}
+		expr += regexp.QuoteMeta(segment[lastIndex:]) + "$"
+
+		re := regexp.MustCompile(expr)
+		r.segments = append(r.segments, lintSegment{
+			re:    re,
+			any:   expr == "^(?:"+RouteParamRequirement+")$",
+			empty: re.MatchString(""),
+		})
+	}
+	return r
+}
+
+func lintRoutes(routes []*route) []RouteIssue {
+	issues := make([]RouteIssue, 0)
+
+	items := make([]*lintRoute, 0, len(routes))
+	for _, route := range routes {
+		item := newLintRoute(route)
+		items = append(items, item)
+
+		// mounted handler also serves its prefix
+		if item != nil && route.mount && len(item.segments) > 1 {
+			prefix := *item
+			prefix.segments = item.segments[:len(item.segments)-1]
+			prefix.catchAll = false
+			prefix.prefix = true
+			items = append(items, &prefix)
+		}
+	}
+
+	// mount may be compared twice, but reported once
+	reported := make(map[[2]*route]bool)
+	for j, b := range items {
+		if b == nil {
+			continue
+		}
+		for _, a := range items[:j] {
+			if a == nil || a.route == b.route || reported[[2]*route{a.route, b.route}] {
+				continue
+			}
+			if !a.overlapsPath(b) || !a.overlapsMethods(b) || !a.overlapsHost(b) {
+				continue
+			}
+			reported[[2]*route{a.route, b.route}] = true
+
+			issue := RouteIssue{Route: b.route.name, Other: a.route.name}
+			// mount is not shadowed while the rest of its path matches
+			if !b.prefix && a.coversPath(b) && a.coversMethods(b) && a.coversHost(b) {
+				issue.Error = true
+				issue.Message = fmt.Sprintf("%s is shadowed by %s and can never match", b.route, a.route)
+			} else {
+				issue.Message = fmt.Sprintf("%s overlaps with %s registered before", b.route, a.route)
+			}
+			issues = append(issues, issue)
+
+			if issue.Error {
+				break
+			}
+		}
+	}
+	return issues
+}
@@ -0,0 +1,57 @@
+package gowl
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestLintRoutes(t *testing.T) {
+	tests := []struct {
+		name   string
+		fn     func(r RouterInterface)
+		issues []string
+	}{
+		{"mount prefix shadows route", func(r RouterInterface) {
+			r.Mount("/m", http.NotFoundHandler())
+			r.GET("/m", testHandler).SetName("m_get")
+			r.GET("/other", testHandler).SetName("other")
+		}, []string{"error: m_get < m"}},
+		{"route overlaps mount prefix", func(r RouterInterface) {
+			r.GET("/m", testHandler).SetName("m_get")
+			r.Mount("/m", http.NotFoundHandler())
+		}, []string{"warning: m < m_get"}},
+		{"mount overlaps catch-all", func(r RouterInterface) {
+			r.GET("/{path...}", testHandler).SetName("all")
+			r.Mount("/m", http.NotFoundHandler())
+		}, []string{"warning: m < all"}},
+		{"different hosts", func(r RouterInterface) {
+			r.GET("/t/{slug}", testHandler).SetHost("{tenant}.example.com").SetName("tenant")
+			r.GET("/t/{slug}", testHandler).SetHost("other.com").SetName("other")
+		}, nil},
+		{"host parameter covers static host", func(r RouterInterface) {
+			r.GET("/t/{slug}", testHandler).SetHost("{tenant}.example.com").SetName("tenant")
+			r.GET("/t/{slug}", testHandler).SetHost("www.example.com").SetName("www")
+		}, []string{"error: www < tenant"}},
+		{"host parameter spanning labels", func(r RouterInterface) {
+			r.GET("/t", testHandler).SetHost("{sub<.+>}.example.com").SetName("sub")
+			r.GET("/t", testHandler).SetHost("a.b.example.com").SetName("ab")
+			r.GET("/t", testHandler).SetHost("example.org").SetName("org")
+		}, []string{"error: ab < sub"}},
+	}
+
+	for _, tt := range tests {
+		compiled, _ := newTestRouters(tt.fn)
+		var issues []string
+		for _, issue := range lintRoutes(compiled.routes) {
+			level := "warning"
+			if issue.Error {
+				level = "error"
+			}
+			issues = append(issues, level+": "+issue.Route+" < "+issue.Other)
+		}
+		if !reflect.DeepEqual(issues, tt.issues) {
+			t.Errorf("%s: got %q, want %q", tt.name, issues, tt.issues)
+		}
+	}
+}
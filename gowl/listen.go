@@ -0,0 +1,97 @@
+package gowl
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	unixAddrPrefix    = "unix:"
+	systemdAddrPrefix = "systemd:"
+
+	// first file descriptor passed by systemd
+	systemdFDStart = 3
+)
+
+var systemd struct {
+	once sync.Once
+	mu   sync.Mutex
+	fds  map[string]int // by index and by name
+}
+
+func listen(addr string) (net.Listener, error) {
+	// reuse socket passed by parent process on restart
+	if ln, err := inheritedListener(addr); ln != nil || err != nil {
+		return ln, err
+	}
+
+	switch {
+	case strings.HasPrefix(addr, unixAddrPrefix):
+		path := addr[len(unixAddrPrefix):]
+
+		// remove stale socket left by a crashed process
+		if fi, err := os.Stat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+			os.Remove(path)
+		}
+		return net.Listen("unix", path)
+	case strings.HasPrefix(addr, systemdAddrPrefix):
+		return systemdListener(addr[len(systemdAddrPrefix):])
+	}
+	return net.Listen("tcp", addr)
+}
+
+func systemdListener(name string) (net.Listener, error) {
+	systemd.once.Do(func() {
+		systemd.fds = make(map[string]int)
+		if pid, err := strconv.Atoi(os.Getenv("LISTEN_PID")); err != nil || pid != os.Getpid() {
+			return
+		}
+		n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+		if err != nil {
+			return
+		}
+		names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+		for i := 0; i < n; i++ {
+			fd := systemdFDStart + i
+			systemd.fds[strconv.Itoa(i)] = fd
+			if i < len(names) && names[i] != "" {
+				if _, ok := systemd.fds[names[i]]; !ok {
+					systemd.fds[names[i]] = fd
+				}
+			}
+		}
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	})
+
+	systemd.mu.Lock()
+	fd, ok := systemd.fds[name]
+	if ok {
+		// descriptor is closed after use, so remove all its aliases
+		for key, v := range systemd.fds {
+			if v == fd {
+				delete(systemd.fds, key)
+			}
+		}
+	}
+	systemd.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf(`gowl: systemd socket "%s" is not passed`, name)
+	}
+
+	f := os.NewFile(uintptr(fd), name)
+	defer f.Close()
+
+	ln, err := net.FileListener(f)
+	if err != nil {
+		return nil, errors.Wrapf(err, `gowl: cannot use systemd socket "%s"`, name)
+	}
+	return ln, nil
+}
@@ -0,0 +1,49 @@
+package gowl
+
+import (
+	"net/http"
+	"net/url"
+)
+
+const mountParam = "mount"
+
+func newMountHandler(handler http.Handler) Handler {
+	return func(r *Request) ResponseInterface {
+		// strip prefix from request path
+		request := new(http.Request)
+		*request = *r.Request
+		request.URL = new(url.URL)
+		*request.URL = *r.URL
+		request.URL.Path = "/" + r.Param(mountParam)
+		request.URL.RawPath = mountRawPath(r.URL.EscapedPath(), request.URL.Path)
+
+		w := newResponseWriter(r.writer)
+		handler.ServeHTTP(w, request)
+
+		statusCode := w.statusCode
+		if statusCode == 0 {
+			statusCode = http.StatusOK
+		}
+		return &writtenResponse{
+			statusCode: statusCode,
+			header:     w.Header(),
+		}
+	}
+}
+
+// mountRawPath returns suffix of escaped request path that matches path of
+// the mounted handler, so escaped slashes like "%2F" are kept.
+func mountRawPath(escaped, path string) string {
+	for i := len(escaped) - 1; i >= 0; i-- {
+		if escaped[i] != '/' {
+			continue
+		}
+		if p, err := url.PathUnescape(escaped[i:]); err == nil && p == path {
+			if escaped[i:] == path {
+				return "" // no escaping to keep
+			}
+			return escaped[i:]
+		}
+	}
+	return ""
+}
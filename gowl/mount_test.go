@@ -0,0 +1,44 @@
+package gowl
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMountEscapedPath(t *testing.T) {
+	s := NewServer(NewConfig()).(*server)
+	router := NewRouter(0)
+	router.Mount("/ext", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.URL.Path+" "+r.URL.EscapedPath())
+	}))
+	s.RegisterRouter(router)
+	s.router.compile()
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/ext/a/b", "/a/b /a/b"},
+		{"/ext/a%2Fb", "/a/b /a%2Fb"},
+		{"/ext/a%20b/c%2Fd", "/a b/c/d /a%20b/c%2Fd"},
+		{"/ext", "/ /"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(GET, tt.path, nil))
+		if body := w.Body.String(); body != tt.want {
+			t.Errorf("%s = %q, want %q", tt.path, body, tt.want)
+		}
+	}
+}
+
+func TestMountNilHandler(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Mount with nil handler does not panic")
+		}
+	}()
+	NewRouter(0).Mount("/ext", nil)
+}
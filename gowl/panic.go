@@ -0,0 +1,66 @@
+package gowl
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// panicInfo
+type panicInfo struct {
+	value interface{}
+	stack []runtime.Frame
+}
+
+func (p *panicInfo) error() error {
+	if err, ok := p.value.(error); ok {
+		return err
+	}
+	return errors.New(fmt.Sprint(p.value))
+}
+
+func (p *panicInfo) String() string {
+	buf := new(strings.Builder)
+	fmt.Fprintf(buf, "panic: %v", p.value)
+	for _, frame := range p.stack {
+		fmt.Fprintf(buf, "\n%s\n\t%s:%d", frame.Function, frame.File, frame.Line)
+	}
+	return buf.String()
+}
+
+// newPanicInfo must be called from deferred function to capture the stack
+// of the panicking goroutine.
+func newPanicInfo(value interface{}, depth int) *panicInfo {
+	p := &panicInfo{value: value}
+	if depth <= 0 {
+		return p
+	}
+
+	// frames of recover and runtime are skipped below
+	pcs := make([]uintptr, depth+32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+
+	var stack []runtime.Frame
+	for {
+		frame, more := frames.Next()
+		stack = append(stack, frame)
+		if !more {
+			break
+		}
+	}
+
+	// start from the panic site
+	for i, frame := range stack {
+		if frame.Function == "runtime.gopanic" {
+			stack = stack[i+1:]
+			break
+		}
+	}
+	if len(stack) > depth {
+		stack = stack[:depth]
+	}
+	p.stack = stack
+	return p
+}
@@ -0,0 +1,148 @@
+package gowl
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// forwardedElement
+type forwardedElement struct {
+	ip    string
+	proto string
+	host  string
+}
+
+func (s *server) trustedProxies() []*net.IPNet {
+	s.proxiesOnce.Do(func() {
+		for _, cidr := range s.config.TrustedProxies {
+			if strings.IndexByte(cidr, '/') == -1 {
+				if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+					cidr += "/32"
+				} else {
+					cidr += "/128"
+				}
+			}
+			_, ipNet, err := net.ParseCIDR(cidr)
+			if err != nil {
+				panic(fmt.Sprintf(`gowl: invalid trusted proxy "%s"`, cidr))
+			}
+			s.proxies = append(s.proxies, ipNet)
+		}
+	})
+	return s.proxies
+}
+
+func (s *server) isTrustedProxy(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, ipNet := range s.trustedProxies() {
+		if ipNet.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveForwarded returns client IP, scheme and host of the request, using
+// forwarding headers only if the request came from a trusted proxy.
+func (s *server) resolveForwarded(r *http.Request) (ip, scheme, host string) {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	scheme, host = "http", r.Host
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if !s.isTrustedProxy(ip) {
+		return
+	}
+
+	var elements []forwardedElement
+	if values := r.Header.Values("Forwarded"); len(values) > 0 {
+		elements = parseForwarded(values)
+	} else if values := r.Header.Values("X-Forwarded-For"); len(values) > 0 {
+		for _, ip := range splitHeaderValues(values) {
+			elements = append(elements, forwardedElement{ip: ip})
+		}
+		if n := len(elements); n > 0 {
+			if protos := splitHeaderValues(r.Header.Values("X-Forwarded-Proto")); len(protos) > 0 {
+				elements[n-1].proto = protos[len(protos)-1]
+			}
+			if hosts := splitHeaderValues(r.Header.Values("X-Forwarded-Host")); len(hosts) > 0 {
+				elements[n-1].host = hosts[len(hosts)-1]
+			}
+		}
+	} else if value := r.Header.Get("X-Real-IP"); value != "" {
+		elements = append(elements, forwardedElement{ip: strings.TrimSpace(value)})
+	}
+
+	// walk right-to-left until the first untrusted address
+	for i := len(elements) - 1; i >= 0; i-- {
+		e := elements[i]
+		if net.ParseIP(e.ip) == nil { // unknown or obfuscated node
+			break
+		}
+		ip = e.ip
+		if e.proto != "" {
+			scheme = strings.ToLower(e.proto)
+		}
+		if e.host != "" {
+			host = e.host
+		}
+		if !s.isTrustedProxy(ip) {
+			break
+		}
+	}
+	return
+}
+
+// parseForwarded parses RFC 7239 Forwarded header values.
+func parseForwarded(values []string) []forwardedElement {
+	var elements []forwardedElement
+	for _, value := range splitHeaderValues(values) {
+		var e forwardedElement
+		for _, pair := range strings.Split(value, ";") {
+			p := strings.IndexByte(pair, '=')
+			if p == -1 {
+				continue
+			}
+			key := strings.ToLower(strings.TrimSpace(pair[:p]))
+			val := strings.Trim(strings.TrimSpace(pair[p+1:]), `"`)
+			switch key {
+			case "for":
+				e.ip = parseForwardedNode(val)
+			case "proto":
+				e.proto = val
+			case "host":
+				e.host = val
+			}
+		}
+		elements = append(elements, e)
+	}
+	return elements
+}
+
+// parseForwardedNode strips port and brackets from node, e.g. "[2001:db8::1]:80".
+func parseForwardedNode(node string) string {
+	if host, _, err := net.SplitHostPort(node); err == nil {
+		return host
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(node, "["), "]")
+}
+
+func splitHeaderValues(values []string) []string {
+	var items []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
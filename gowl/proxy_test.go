@@ -0,0 +1,98 @@
+package gowl
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestResolveForwarded(t *testing.T) {
+	config := NewConfig()
+	config.TrustedProxies = []string{"10.0.0.0/8", "::1"}
+	s := NewServer(config).(*server)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		header     map[string]string
+		ip         string
+		scheme     string
+		host       string
+	}{
+		{"no headers", "203.0.113.5:1234", nil, "203.0.113.5", "http", "example.com"},
+		{"spoofed XFF from untrusted peer", "203.0.113.5:1234", map[string]string{
+			"X-Forwarded-For":   "1.2.3.4",
+			"X-Forwarded-Proto": "https",
+			"X-Forwarded-Host":  "evil.com",
+		}, "203.0.113.5", "http", "example.com"},
+		{"XFF from trusted peer", "10.0.0.1:1234", map[string]string{
+			"X-Forwarded-For":   "1.2.3.4",
+			"X-Forwarded-Proto": "HTTPS",
+			"X-Forwarded-Host":  "www.example.com",
+		}, "1.2.3.4", "https", "www.example.com"},
+		{"XFF chain of trusted proxies", "10.0.0.1:1234", map[string]string{
+			"X-Forwarded-For": "6.6.6.6, 1.2.3.4, 10.0.0.2",
+		}, "1.2.3.4", "http", "example.com"},
+		{"XFF with irregular spacing", "10.0.0.1:1234", map[string]string{
+			"X-Forwarded-For": "6.6.6.6, 1.2.3.4,10.0.0.3 , 10.0.0.2",
+		}, "1.2.3.4", "http", "example.com"},
+		{"XFF of trusted proxies only", "10.0.0.1:1234", map[string]string{
+			"X-Forwarded-For": "10.0.0.3, 10.0.0.2",
+		}, "10.0.0.3", "http", "example.com"},
+		{"XFF with invalid address", "10.0.0.1:1234", map[string]string{
+			"X-Forwarded-For": "1.2.3.4, unknown",
+		}, "10.0.0.1", "http", "example.com"},
+		{"Forwarded with quoted IPv6", "10.0.0.1:1234", map[string]string{
+			"Forwarded": `for="[2001:db8::1]:80";proto=https;host="www.example.com"`,
+		}, "2001:db8::1", "https", "www.example.com"},
+		{"Forwarded with IPv6 loopback", "10.0.0.1:1234", map[string]string{
+			"Forwarded": `For="[::1]:80", for=10.0.0.2`,
+		}, "::1", "http", "example.com"},
+		{"Forwarded chain", "10.0.0.1:1234", map[string]string{
+			"Forwarded": `for=6.6.6.6, for=1.2.3.4;proto=https, for=10.0.0.2`,
+		}, "1.2.3.4", "https", "example.com"},
+		{"Forwarded with obfuscated node", "10.0.0.1:1234", map[string]string{
+			"Forwarded": `for=1.2.3.4, for=_hidden`,
+		}, "10.0.0.1", "http", "example.com"},
+		{"Forwarded takes precedence", "10.0.0.1:1234", map[string]string{
+			"Forwarded":       `for=1.2.3.4`,
+			"X-Forwarded-For": "5.6.7.8",
+		}, "1.2.3.4", "http", "example.com"},
+		{"X-Real-IP from trusted peer", "10.0.0.1:1234", map[string]string{
+			"X-Real-IP": " 1.2.3.4 ",
+		}, "1.2.3.4", "http", "example.com"},
+		{"X-Real-IP from untrusted peer", "203.0.113.5:1234", map[string]string{
+			"X-Real-IP": "1.2.3.4",
+		}, "203.0.113.5", "http", "example.com"},
+		{"trusted IPv6 peer", "[::1]:1234", map[string]string{
+			"X-Forwarded-For": "2001:db8::1",
+		}, "2001:db8::1", "http", "example.com"},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(GET, "http://example.com/", nil)
+		r.RemoteAddr = tt.remoteAddr
+		for key, value := range tt.header {
+			r.Header.Set(key, value)
+		}
+		ip, scheme, host := s.resolveForwarded(r)
+		if ip != tt.ip || scheme != tt.scheme || host != tt.host {
+			t.Errorf("%s: got (%s, %s, %s), want (%s, %s, %s)", tt.name, ip, scheme, host, tt.ip, tt.scheme, tt.host)
+		}
+	}
+}
+
+func TestParseForwarded(t *testing.T) {
+	got := parseForwarded([]string{
+		`for=192.0.2.60;proto=http;by=203.0.113.43`,
+		`For="[2001:db8:cafe::17]:4711", for=unknown ; HOST=example.com`,
+	})
+	want := []forwardedElement{
+		{ip: "192.0.2.60", proto: "http"},
+		{ip: "2001:db8:cafe::17"},
+		{ip: "unknown", host: "example.com"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
@@ -1,10 +1,14 @@
 package gowl
 
 import (
+	"context"
+	"crypto/x509"
 	"html/template"
 	"net"
 	"net/http"
+	"net/url"
 	"strings"
+	"time"
 
 	"github.com/lokhman/gowl/types"
 )
@@ -25,11 +29,26 @@ type Request struct {
 	*http.Request
 
 	server *server
+	writer http.ResponseWriter
+	route  *route
 	params types.StringMap
+	values types.Data
+
+	id       string
+	clientIP string
+	panicked bool
 
 	Data types.Data
 }
 
+// PeerCertificate returns verified client certificate or nil.
+func (r *Request) PeerCertificate() *x509.Certificate {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return r.TLS.VerifiedChains[0][0]
+}
+
 func (r *Request) Param(name string) string {
 	return r.params.Get(name)
 }
@@ -38,31 +57,85 @@ func (r *Request) Params() types.StringMap {
 	return r.params.Copy()
 }
 
-func (r *Request) ClientIP() (ip string) {
-	if ip = r.Header.Get("X-Forwarded-For"); ip != "" {
-		if p := strings.IndexByte(ip, ','); p != -1 {
-			ip = ip[:p]
-		}
-		if ip = strings.TrimSpace(ip); ip != "" {
-			return
-		}
+// ParamValue returns the converted value of the parameter, or its string
+// value if the parameter has no converter.
+func (r *Request) ParamValue(name string) interface{} {
+	if value, ok := r.values.Lookup(name); ok {
+		return value
 	}
-	if ip = r.Header.Get("X-Real-IP"); ip != "" {
-		return
+	if value, ok := r.params.Lookup(name); ok {
+		return value
+	}
+	return nil
+}
+
+func (r *Request) ParamInt(name string) int {
+	return Param[int](r, name)
+}
+
+func (r *Request) ParamTime(name string) time.Time {
+	return Param[time.Time](r, name)
+}
+
+// WithContext returns a shallow copy of the request with its context changed
+// to ctx, keeping route parameters and Data of the original request.
+func (r *Request) WithContext(ctx context.Context) *Request {
+	r2 := new(Request)
+	*r2 = *r
+	r2.Request = r.Request.WithContext(ctx)
+	return r2
+}
+
+// ID returns the request correlation ID.
+func (r *Request) ID() string {
+	return r.id
+}
+
+// ClientIP returns the client address, which is taken from forwarding headers
+// only if the request came from a trusted proxy.
+func (r *Request) ClientIP() string {
+	if r.clientIP != "" {
+		return r.clientIP
 	}
-	ip, _, _ = net.SplitHostPort(r.RemoteAddr)
-	return
+	ip, _, _ := net.SplitHostPort(r.RemoteAddr)
+	return ip
 }
 
 func (r *Request) GetURL(name string, params types.StringMap, absolute bool) string {
-	url := r.server.router.url(name, params)
-	if absolute {
-		url.Scheme = r.URL.Scheme
-		url.Host = r.Host
+	data := make(types.Data, len(params))
+	for key, value := range params {
+		data[key] = value
+	}
+
+	url, err := r.BuildURL(name, data, absolute)
+	if err != nil {
+		panic(err.Error())
 	}
 	return url.String()
 }
 
+func (r *Request) BuildURL(name string, params types.Data, absolute bool) (*url.URL, error) {
+	url, err := r.server.router.url(name, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if url.Host == "" {
+		url.Host = r.Host
+	} else if _, port, err := net.SplitHostPort(r.Host); err == nil && strings.IndexByte(url.Host, ':') == -1 {
+		// keep port of the current request
+		url.Host = net.JoinHostPort(url.Host, port)
+	}
+
+	// route on another host always requires absolute URL
+	if absolute || !strings.EqualFold(url.Host, r.Host) {
+		url.Scheme = r.URL.Scheme
+	} else {
+		url.Host = ""
+	}
+	return url, nil
+}
+
 func (r *Request) Template() *template.Template {
 	if path := r.params.Get(":route"); path != "" {
 		name := strings.Replace(path, ".", "/", -1)
@@ -70,3 +143,10 @@ func (r *Request) Template() *template.Template {
 	}
 	return nil
 }
+
+// Param returns the converted value of the request parameter as T, or the
+// zero value if the parameter is missing or has another type.
+func Param[T any](r *Request, name string) T {
+	value, _ := r.ParamValue(name).(T)
+	return value
+}
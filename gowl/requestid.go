@@ -0,0 +1,31 @@
+package gowl
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// maximum length of accepted incoming request ID
+const maxRequestIDLength = 128
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// isValidRequestID allows only visible ASCII characters, so incoming ID
+// cannot be used to inject data into logs or headers.
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
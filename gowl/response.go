@@ -105,6 +105,29 @@ func EmptyResponse() ResponseInterface {
 	return &emptyResponse{}
 }
 
+// writtenResponse
+type writtenResponse struct {
+	statusCode int
+	header     http.Header
+}
+
+func (r *writtenResponse) StatusCode() int {
+	return r.statusCode
+}
+
+func (r *writtenResponse) Header() http.Header {
+	return r.header
+}
+
+func (r *writtenResponse) Write(_ io.Writer) error {
+	return nil
+}
+
+func (r *writtenResponse) WriteResponse(_ http.ResponseWriter) error {
+	// response is already written directly to the connection
+	return nil
+}
+
 // redirectResponse
 type redirectResponse struct {
 	request    *Request
@@ -0,0 +1,166 @@
+package gowl
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// environment of a restarted process
+	envListeners = "GOWL_LISTENERS" // "addr=fd" pairs separated by ";"
+	envReadyFD   = "GOWL_READY_FD"
+
+	// time given to a restarted process to start all servers
+	restartTimeout = 30 * time.Second
+)
+
+var inherited struct {
+	once sync.Once
+	mu   sync.Mutex
+	fds  map[string]int
+}
+
+// fileListener
+type fileListener interface {
+	File() (*os.File, error)
+}
+
+func inheritedListener(addr string) (net.Listener, error) {
+	inherited.once.Do(func() {
+		inherited.fds = make(map[string]int)
+		for _, pair := range strings.Split(os.Getenv(envListeners), ";") {
+			i := strings.LastIndexByte(pair, '=')
+			if i == -1 {
+				continue
+			}
+			if fd, err := strconv.Atoi(pair[i+1:]); err == nil {
+				inherited.fds[pair[:i]] = fd
+			}
+		}
+		os.Unsetenv(envListeners)
+	})
+
+	inherited.mu.Lock()
+	fd, ok := inherited.fds[addr]
+	delete(inherited.fds, addr)
+	inherited.mu.Unlock()
+	if !ok {
+		return nil, nil
+	}
+
+	f := os.NewFile(uintptr(fd), addr)
+	defer f.Close()
+
+	ln, err := net.FileListener(f)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot inherit listener %s", addr)
+	}
+
+	// remove socket file when the last process stops
+	if ln, ok := ln.(*net.UnixListener); ok {
+		ln.SetUnlinkOnClose(true)
+	}
+	return ln, nil
+}
+
+func notifyReady() {
+	fd, err := strconv.Atoi(os.Getenv(envReadyFD))
+	os.Unsetenv(envReadyFD)
+	if err != nil {
+		return
+	}
+
+	f := os.NewFile(uintptr(fd), "ready")
+	defer f.Close()
+	f.Write([]byte{1})
+}
+
+func restart(servers map[string]ServerInterface) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return errors.Wrap(err, "cannot restart")
+	}
+
+	var files []*os.File
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+
+	pairs := make([]string, 0, len(servers))
+	for addr, server := range servers {
+		listeners := server.getListeners()
+		if len(listeners) == 0 {
+			return fmt.Errorf(`cannot restart: server "%s" is not listening`, addr)
+		}
+		for addr, ln := range listeners {
+			// keep socket file for the new process
+			if ln, ok := ln.(*net.UnixListener); ok {
+				ln.SetUnlinkOnClose(false)
+			}
+			f, err := ln.(fileListener).File()
+			if err != nil {
+				return errors.Wrapf(err, `cannot restart: listener "%s"`, addr)
+			}
+			files = append(files, f)
+
+			// extra files start after stdin, stdout and stderr
+			pairs = append(pairs, fmt.Sprintf("%s=%d", addr, len(files)+2))
+		}
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return errors.Wrap(err, "cannot restart")
+	}
+	defer r.Close()
+	files = append(files, w)
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = files
+	cmd.Env = append(os.Environ(),
+		envListeners+"="+strings.Join(pairs, ";"),
+		envReadyFD+"="+strconv.Itoa(len(files)+2),
+	)
+	if err := cmd.Start(); err != nil {
+		return errors.Wrap(err, "cannot restart")
+	}
+	w.Close()
+	files = files[:len(files)-1]
+
+	// child closes pipe without writing if it fails to start
+	ready := make(chan error, 1)
+	go func() {
+		_, err := r.Read(make([]byte, 1))
+		if err == io.EOF {
+			err = errors.New("process exited before it was ready")
+		}
+		ready <- err
+	}()
+
+	select {
+	case err = <-ready:
+	case <-time.After(restartTimeout):
+		err = errors.New("process was not ready in time")
+	}
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return errors.Wrapf(err, "cannot restart: pid %d", cmd.Process.Pid)
+	}
+	cmd.Process.Release()
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+
+package gowl
+
+import (
+	"os"
+	"syscall"
+)
+
+var restartSignals = []os.Signal{syscall.SIGHUP, syscall.SIGUSR2}
+
+// SIGHUP is taken by restart, so certificates are reloaded on SIGUSR1
+var reloadSignals = []os.Signal{syscall.SIGUSR1}
@@ -0,0 +1,11 @@
+//go:build windows
+
+package gowl
+
+import "os"
+
+// restart by listener handoff is not supported on Windows
+var restartSignals []os.Signal
+
+// certificates are reloaded only by CertReloadInterval on Windows
+var reloadSignals []os.Signal
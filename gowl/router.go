@@ -2,14 +2,20 @@ package gowl
 
 import (
 	"fmt"
+	"net/http"
 	"net/url"
+	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/lokhman/gowl/events"
 	"github.com/lokhman/gowl/helpers"
+	"github.com/lokhman/gowl/httputil"
 	"github.com/lokhman/gowl/types"
+	"github.com/pkg/errors"
 )
 
 const (
@@ -20,20 +26,65 @@ const (
 	RedirectTrailingSlash
 )
 
-const RouteParamRequirement = `[^/]+`
+const (
+	RouteParamRequirement     = `[^/]+`
+	RouteCatchAllRequirement  = `.*`
+	RouteHostParamRequirement = `[^.]+`
+)
 
-var reRoutePathParams = regexp.MustCompile(`{([a-z0-9_]+)(?:<(.*?)>)?(?:\?([^}]+))?}`)
+var reRoutePathParams = regexp.MustCompile(`{([a-z0-9_]+)(\.\.\.)?(:[a-z0-9_]+)?(?:<(.*?)>)?(?:\?([^}]+))?}`)
 var reRouteParamRequirement = regexp.MustCompile(`^` + RouteParamRequirement + `$`)
+var reRouteCatchAllRequirement = regexp.MustCompile(`^` + RouteCatchAllRequirement + `$`)
+
+var (
+	ErrRouteNotFound = errors.New("gowl: route not found")
+	ErrMissingParam  = errors.New("gowl: parameter is missing")
+	ErrInvalidParam  = errors.New("gowl: parameter has invalid value")
+)
+
+// URLError
+type URLError struct {
+	Route string
+	Param string
+	Value string
+	Err   error
+}
+
+func (e *URLError) Error() string {
+	switch e.Err {
+	case ErrRouteNotFound:
+		return fmt.Sprintf(`gowl: cannot find route with name "%s"`, e.Route)
+	case ErrMissingParam:
+		return fmt.Sprintf(`gowl: parameter "%s" is missing in path "%s"`, e.Param, e.Route)
+	case ErrInvalidParam:
+		return fmt.Sprintf(`gowl: parameter "%s" in path "%s" has invalid value "%s"`, e.Param, e.Route, e.Value)
+	}
+	return e.Err.Error()
+}
+
+func (e *URLError) Unwrap() error {
+	return e.Err
+}
 
 // Handler
 type Handler func(r *Request) ResponseInterface
 
+// HandlerWithError
+type HandlerWithError func(r *Request) (ResponseInterface, error)
+
+// Middleware
+type Middleware func(next Handler) Handler
+
 // ParamAttributes
 type ParamAttributes struct {
 	Requirement  string
 	DefaultValue string
+	Converter    string
 
+	converter     ConverterInterface
 	reRequirement *regexp.Regexp
+	spanSegments  bool
+	catchAll      bool
 	compiled      bool
 }
 
@@ -43,9 +94,13 @@ type Params map[string]ParamAttributes
 // RouteInterface
 type RouteInterface interface {
 	SetName(name string) RouteInterface
+	SetHost(host string) RouteInterface
 	AddParam(name string, attr ParamAttributes) RouteInterface
 	SetParams(params Params) RouteInterface
 	SetFlag(flag types.Flag) RouteInterface
+	SetMaxBodySize(size int64) RouteInterface
+	SetTimeout(timeout time.Duration) RouteInterface
+	Use(middleware ...Middleware) RouteInterface
 	On(eventType events.EventType, listener func(event EventInterface)) RouteInterface
 	String() string
 
@@ -58,15 +113,28 @@ type route struct {
 
 	name    string
 	methods []string
+	host    string
 	path    string
 	handler Handler
 	params  Params
 	flags   types.Flag
 
+	handlerName string // name of the registered function
+
+	maxBodySize  int64
+	timeout      time.Duration
+	errorHandler ErrorHandler
+
+	middleware []Middleware
+	chain      Handler
+
+	reHost   *regexp.Regexp
 	rePath   *regexp.Regexp
-	rePrefix string
+	catchAll string
 
+	hostPort      bool
 	trailingSlash bool
+	mount         bool
 	compiled      bool
 }
 
@@ -75,6 +143,11 @@ func (r *route) SetName(name string) RouteInterface {
 	return r
 }
 
+func (r *route) SetHost(host string) RouteInterface {
+	r.host = host
+	return r
+}
+
 func (r *route) AddParam(name string, attr ParamAttributes) RouteInterface {
 	if _, ok := r.params[name]; ok {
 		panic(fmt.Sprintf(`gowl: path "%s" has a duplicated parameter "%s"`, r.path, name))
@@ -99,6 +172,21 @@ func (r *route) SetFlag(flag types.Flag) RouteInterface {
 	return r
 }
 
+func (r *route) SetMaxBodySize(size int64) RouteInterface {
+	r.maxBodySize = size
+	return r
+}
+
+func (r *route) SetTimeout(timeout time.Duration) RouteInterface {
+	r.timeout = timeout
+	return r
+}
+
+func (r *route) Use(middleware ...Middleware) RouteInterface {
+	r.middleware = append(r.middleware, middleware...)
+	return r
+}
+
 func (r *route) On(eventType events.EventType, listener func(event EventInterface)) RouteInterface {
 	r.emitter.On(eventType, listener)
 	return r
@@ -111,7 +199,7 @@ func (r *route) String() string {
 	} else if len(r.methods) > 1 {
 		method = "[" + strings.Join(r.methods, "|") + "]"
 	}
-	return fmt.Sprintf("%s %s (%s)", method, r.path, r.name)
+	return fmt.Sprintf("%s %s%s (%s)", method, r.host, r.path, r.name)
 }
 
 func (r *route) compile() bool {
@@ -120,7 +208,10 @@ func (r *route) compile() bool {
 	}
 
 	if r.name == "" {
-		name := helpers.GetFuncName(r.handler)
+		name := r.handlerName
+		if name == "" {
+			name = helpers.GetFuncName(r.handler)
+		}
 		name = strings.TrimPrefix(name, "main.")
 		name = strings.TrimSuffix(name, "-fm")
 		name = helpers.ToUnderscore(name)
@@ -143,42 +234,43 @@ func (r *route) compile() bool {
 	}
 
 	var err error
-	path, rePath, lastIndex, paramCount := r.path, "", 0, 0
-	if strings.IndexByte(path, '{') != strings.IndexByte(path, '}') { // -1 != -1
-		r.path = helpers.ReplaceAllStringSubmatchFunc(reRoutePathParams, path, func(m []string, i int) string {
-			attr, ok := r.params[m[1]]
-			if ok && attr.compiled {
-				panic(fmt.Sprintf(`gowl: path "%s" has a duplicated parameter "%s"`, path, m[1]))
-			} else if !ok || attr.Requirement == "" {
-				attr.Requirement = RouteParamRequirement
-			}
-
+	host, reHost, hostParamCount := r.host, "", 0
+	if host != "" {
+		lastIndex := 0
+		r.host = helpers.ReplaceAllStringSubmatchFunc(reRoutePathParams, host, func(m []string, i int) string {
 			if m[2] != "" {
-				// extract from path
-				attr.Requirement = m[2]
-			}
-			if attr.Requirement == RouteParamRequirement {
-				attr.reRequirement = reRouteParamRequirement
-			} else { // compile parameter requirement if not default given
-				if attr.reRequirement, err = regexp.Compile(`^` + attr.Requirement + `$`); err != nil {
-					panic(fmt.Sprintf(`gowl: path "%s" has invalid parameter "%s": %s`, path, m[1], err.Error()))
-				}
+				panic(fmt.Sprintf(`gowl: host "%s" must not have catch-all parameter "%s"`, host, m[1]))
 			}
+			attr := r.compileParam("host", host, m, RouteHostParamRequirement)
 
-			if m[3] != "" {
-				// extract from path
-				attr.DefaultValue = m[3]
-			}
-			if attr.DefaultValue != "" && !attr.reRequirement.MatchString(attr.DefaultValue) {
-				panic(fmt.Sprintf(`gowl: path "%s" has invalid default value "%s"`, path, attr.DefaultValue))
-			}
+			reHost += regexp.QuoteMeta(host[lastIndex:i])
+			reHost += "(?P<" + m[1] + ">" + attr.Requirement + ")"
 
-			attr.compiled = true
-			r.params[m[1]] = attr
+			lastIndex = i + len(m[0])
+			hostParamCount++
 
-			if paramCount == 0 {
-				r.rePrefix = path[:strings.Index(path, m[0])]
+			return "{" + m[1] + m[3] + "}"
+		})
+		reHost += regexp.QuoteMeta(host[lastIndex:])
+		if r.reHost, err = regexp.Compile("(?i)^" + reHost + "$"); err != nil {
+			panic(fmt.Sprintf(`gowl: host "%s" cannot be compiled: %s`, host, err.Error()))
+		}
+		r.hostPort = strings.IndexByte(r.host, ':') != -1
+	}
+
+	path, rePath, lastIndex, paramCount := r.path, "", 0, 0
+	if strings.IndexByte(path, '{') != strings.IndexByte(path, '}') { // -1 != -1
+		r.path = helpers.ReplaceAllStringSubmatchFunc(reRoutePathParams, path, func(m []string, i int) string {
+			requirement := RouteParamRequirement
+			if m[2] != "" {
+				// catch-all parameter must be the last segment
+				if (i > 0 && path[i-1] != '/') || i+len(m[0]) != len(path) {
+					panic(fmt.Sprintf(`gowl: path "%s" must have catch-all parameter "%s" as the last segment`, path, m[1]))
+				}
+				requirement = RouteCatchAllRequirement
+				r.catchAll = m[1]
 			}
+			attr := r.compileParam("path", path, m, requirement)
 
 			rePath += regexp.QuoteMeta(path[lastIndex:i])
 			rePath += "(?P<" + m[1] + ">" + attr.Requirement + ")"
@@ -186,11 +278,11 @@ func (r *route) compile() bool {
 			lastIndex = i + len(m[0])
 			paramCount++
 
-			return "{" + m[1] + "}"
+			return "{" + m[1] + m[2] + m[3] + "}"
 		})
 	}
 
-	if n := len(r.params) - paramCount; n != 0 {
+	if n := len(r.params) - paramCount - hostParamCount; n != 0 {
 		panic(fmt.Sprintf(`gowl: path "%s" has %d unused parameter(s)`, path, n))
 	}
 
@@ -213,6 +305,161 @@ func (r *route) compile() bool {
 	return true
 }
 
+func (r *route) compileParam(kind, pattern string, m []string, requirement string) ParamAttributes {
+	attr, ok := r.params[m[1]]
+	if ok && attr.compiled {
+		panic(fmt.Sprintf(`gowl: %s "%s" has a duplicated parameter "%s"`, kind, pattern, m[1]))
+	}
+
+	if m[3] != "" {
+		// extract from pattern
+		attr.Converter = m[3][1:]
+	}
+	if attr.Converter != "" {
+		if attr.converter = getConverter(attr.Converter); attr.converter == nil {
+			panic(fmt.Sprintf(`gowl: %s "%s" has unknown converter "%s" for parameter "%s"`, kind, pattern, attr.Converter, m[1]))
+		}
+		requirement = attr.converter.Requirement()
+	}
+
+	if m[4] != "" {
+		// extract from pattern
+		attr.Requirement = m[4]
+	} else if attr.Requirement == "" {
+		attr.Requirement = requirement
+	}
+	switch attr.Requirement {
+	case RouteParamRequirement:
+		attr.reRequirement = reRouteParamRequirement
+	case RouteCatchAllRequirement:
+		attr.reRequirement = reRouteCatchAllRequirement
+	default: // compile parameter requirement if not default given
+		var err error
+		if attr.reRequirement, err = regexp.Compile(`^` + attr.Requirement + `$`); err != nil {
+			panic(fmt.Sprintf(`gowl: %s "%s" has invalid parameter "%s": %s`, kind, pattern, m[1], err.Error()))
+		}
+	}
+
+	if m[5] != "" {
+		// extract from pattern
+		attr.DefaultValue = m[5]
+	}
+	if attr.DefaultValue != "" && !attr.reRequirement.MatchString(attr.DefaultValue) {
+		panic(fmt.Sprintf(`gowl: %s "%s" has invalid default value "%s"`, kind, pattern, attr.DefaultValue))
+	}
+
+	attr.spanSegments = helpers.RegexpCanMatchRune(attr.Requirement, '/')
+	attr.catchAll = m[2] != ""
+	attr.compiled = true
+	r.params[m[1]] = attr
+	return attr
+}
+
+func (r *route) matchHost(host string, params types.StringMap) bool {
+	if r.reHost == nil {
+		return true
+	}
+	if !r.hostPort {
+		host = httputil.StripPort(host)
+	}
+	match := r.reHost.FindStringSubmatch(strings.ToLower(host))
+	if match == nil {
+		return false
+	}
+	for i, name := range r.reHost.SubexpNames()[1:] {
+		if name != "" {
+			r.setParam(params, name, match[i+1])
+		}
+	}
+	return true
+}
+
+func (r *route) urlParam(name, param string, params types.Data) (string, error) {
+	attr := r.params[param]
+	v, ok := params[param]
+	if !ok { // if parameter not given, try to pick default
+		if attr.DefaultValue == "" {
+			return "", &URLError{Route: name, Param: param, Err: ErrMissingParam}
+		}
+		return attr.DefaultValue, nil
+	}
+
+	value, err := r.formatParam(param, v)
+	if err != nil {
+		return "", &URLError{Route: name, Param: param, Value: fmt.Sprint(v), Err: ErrInvalidParam}
+	}
+	if !attr.reRequirement.MatchString(value) {
+		return "", &URLError{Route: name, Param: param, Value: value, Err: ErrInvalidParam}
+	}
+	return value, nil
+}
+
+func (r *route) formatParam(name string, value interface{}) (string, error) {
+	if v, ok := value.(string); ok {
+		return v, nil
+	}
+	if converter := r.params[name].converter; converter != nil {
+		return converter.Format(value)
+	}
+	return fmt.Sprint(value), nil
+}
+
+func (r *route) convertParams(params types.StringMap) (values types.Data, err error) {
+	for name, attr := range r.params {
+		if attr.converter == nil {
+			continue
+		}
+		var value interface{}
+		if value, err = attr.converter.Parse(params.Get(name)); err != nil {
+			return nil, fmt.Errorf(`gowl: parameter "%s" cannot be converted: %s`, name, err.Error())
+		}
+		values.Set(name, value)
+	}
+	return
+}
+
+func (r *route) setParam(params types.StringMap, name, value string) {
+	if value == "" {
+		value = r.params[name].DefaultValue
+	}
+	params.Set(name, value)
+}
+
+var (
+	handlerType          = reflect.TypeOf(Handler(nil))
+	handlerWithErrorType = reflect.TypeOf(HandlerWithError(nil))
+)
+
+// newHandler accepts any function convertible to Handler or HandlerWithError.
+func newHandler(handler interface{}) Handler {
+	switch h := handler.(type) {
+	case Handler:
+		return h
+	case HandlerWithError:
+		return newHandlerWithError(h)
+	}
+
+	if v := reflect.ValueOf(handler); v.Kind() == reflect.Func && !v.IsNil() {
+		switch {
+		case v.Type().ConvertibleTo(handlerType):
+			return v.Convert(handlerType).Interface().(Handler)
+		case v.Type().ConvertibleTo(handlerWithErrorType):
+			return newHandlerWithError(v.Convert(handlerWithErrorType).Interface().(HandlerWithError))
+		}
+	}
+	panic(fmt.Sprintf("gowl: handler of type %T must be Handler or HandlerWithError", handler))
+}
+
+func newHandlerWithError(handler HandlerWithError) Handler {
+	return func(r *Request) ResponseInterface {
+		response, err := handler(r)
+		if err != nil {
+			return r.server.handleError(r, err)
+		}
+		return response
+	}
+}
+
 func newRoute(methods []string, path string, handler Handler) *route {
 	return &route{
 		emitter: make(events.Emitter),
@@ -226,32 +473,60 @@ func newRoute(methods []string, path string, handler Handler) *route {
 
 // RouterInterface
 type RouterInterface interface {
+	SetName(name string)
+	SetHost(host string)
 	SetPrefix(path string)
 	SetFlag(flag types.Flag)
-
-	Match(path string, handler Handler, method ...string) RouteInterface
-	HEAD(path string, handler Handler) RouteInterface
-	GET(path string, handler Handler) RouteInterface
-	POST(path string, handler Handler) RouteInterface
-	PUT(path string, handler Handler) RouteInterface
-	PATCH(path string, handler Handler) RouteInterface
-	DELETE(path string, handler Handler) RouteInterface
-	OPTIONS(path string, handler Handler) RouteInterface
-	TRACE(path string, handler Handler) RouteInterface
-	CONNECT(path string, handler Handler) RouteInterface
-
+	SetErrorHandler(handler ErrorHandler)
+
+	// handler can be Handler or HandlerWithError
+	Match(path string, handler interface{}, method ...string) RouteInterface
+	HEAD(path string, handler interface{}) RouteInterface
+	GET(path string, handler interface{}) RouteInterface
+	POST(path string, handler interface{}) RouteInterface
+	PUT(path string, handler interface{}) RouteInterface
+	PATCH(path string, handler interface{}) RouteInterface
+	DELETE(path string, handler interface{}) RouteInterface
+	OPTIONS(path string, handler interface{}) RouteInterface
+	TRACE(path string, handler interface{}) RouteInterface
+	CONNECT(path string, handler interface{}) RouteInterface
+	Group(prefix string, fn func(r RouterInterface)) RouterInterface
+	Mount(prefix string, handler http.Handler) RouteInterface
+
+	Use(middleware ...Middleware)
 	On(eventType events.EventType, listener func(event EventInterface))
 
 	compile() (routes []*route, ok bool)
 }
 
+// routerGroup
+type routerGroup struct {
+	index  int
+	router *router
+}
+
 // router
 type router struct {
-	emitter  events.Emitter
+	emitter    events.Emitter
+	middleware []Middleware
+
 	routes   []*route
+	groups   []routerGroup
+	name     string
+	host     string
 	prefix   string
 	flags    types.Flag
 	compiled bool
+
+	errorHandler ErrorHandler
+}
+
+func (r *router) SetName(name string) {
+	r.name = name
+}
+
+func (r *router) SetHost(host string) {
+	r.host = host
 }
 
 func (r *router) SetPrefix(path string) {
@@ -266,67 +541,114 @@ func (r *router) SetFlag(flag types.Flag) {
 	r.flags.Set(flag)
 }
 
-func (r *router) Match(path string, handler Handler, method ...string) RouteInterface {
-	route := newRoute(method, path, handler)
+func (r *router) SetErrorHandler(handler ErrorHandler) {
+	r.errorHandler = handler
+}
+
+func (r *router) Match(path string, handler interface{}, method ...string) RouteInterface {
+	route := newRoute(method, path, newHandler(handler))
+	route.handlerName = helpers.GetFuncName(handler)
 	r.routes = append(r.routes, route)
 	return route
 }
 
-func (r *router) HEAD(path string, handler Handler) RouteInterface {
+func (r *router) HEAD(path string, handler interface{}) RouteInterface {
 	return r.Match(path, handler, HEAD)
 }
 
-func (r *router) GET(path string, handler Handler) RouteInterface {
+func (r *router) GET(path string, handler interface{}) RouteInterface {
 	return r.Match(path, handler, GET)
 }
 
-func (r *router) POST(path string, handler Handler) RouteInterface {
+func (r *router) POST(path string, handler interface{}) RouteInterface {
 	return r.Match(path, handler, POST)
 }
 
-func (r *router) PUT(path string, handler Handler) RouteInterface {
+func (r *router) PUT(path string, handler interface{}) RouteInterface {
 	return r.Match(path, handler, PUT)
 }
 
-func (r *router) PATCH(path string, handler Handler) RouteInterface {
+func (r *router) PATCH(path string, handler interface{}) RouteInterface {
 	return r.Match(path, handler, PATCH)
 }
 
-func (r *router) DELETE(path string, handler Handler) RouteInterface {
+func (r *router) DELETE(path string, handler interface{}) RouteInterface {
 	return r.Match(path, handler, DELETE)
 }
 
-func (r *router) OPTIONS(path string, handler Handler) RouteInterface {
+func (r *router) OPTIONS(path string, handler interface{}) RouteInterface {
 	return r.Match(path, handler, OPTIONS)
 }
 
-func (r *router) TRACE(path string, handler Handler) RouteInterface {
+func (r *router) TRACE(path string, handler interface{}) RouteInterface {
 	return r.Match(path, handler, TRACE)
 }
 
-func (r *router) CONNECT(path string, handler Handler) RouteInterface {
+func (r *router) CONNECT(path string, handler interface{}) RouteInterface {
 	return r.Match(path, handler, CONNECT)
 }
 
+func (r *router) Group(prefix string, fn func(r RouterInterface)) RouterInterface {
+	assertPath(prefix)
+
+	group := &router{
+		emitter: make(events.Emitter),
+		routes:  make([]*route, 0),
+		name:    getPrefixName(prefix),
+		prefix:  prefix,
+		flags:   defaultState,
+	}
+	r.groups = append(r.groups, routerGroup{len(r.routes), group})
+	fn(group)
+	return group
+}
+
+func (r *router) Mount(prefix string, handler http.Handler) RouteInterface {
+	assertPath(prefix)
+	if handler == nil {
+		panic(fmt.Sprintf(`gowl: mount "%s" must have a handler`, prefix))
+	}
+
+	route := newRoute(nil, joinPath(prefix, "/{"+mountParam+"...}"), newMountHandler(handler))
+	if route.name = getPrefixName(prefix); route.name == "" {
+		route.name = mountParam
+	}
+	route.mount = true
+	r.routes = append(r.routes, route)
+	return route
+}
+
+func (r *router) Use(middleware ...Middleware) {
+	r.middleware = append(r.middleware, middleware...)
+}
+
 func (r *router) On(eventType events.EventType, listener func(event EventInterface)) {
 	r.emitter.On(eventType, listener)
 }
 
 func (r *router) compile() (routes []*route, ok bool) {
-	routes = r.routes
 	if r.compiled {
 		return
 	}
 
-	for _, route := range routes {
-		if n := len(r.prefix); n > 1 {
-			path := route.path
-			route.path = r.prefix
-			if r.prefix[n-1] == '/' {
-				route.path += path[1:]
-			} else if path != "/" {
-				route.path += path
-			}
+	routes = make([]*route, 0, len(r.routes))
+	groups := r.groups
+	for i := 0; i <= len(r.routes); i++ {
+		// keep group routes in the order of registration
+		for len(groups) > 0 && groups[0].index == i {
+			routes = append(routes, r.compileGroup(groups[0].router)...)
+			groups = groups[1:]
+		}
+		if i == len(r.routes) {
+			break
+		}
+
+		route := r.routes[i]
+		route.path = joinPath(r.prefix, route.path)
+
+		// inherit host if not set
+		if route.host == "" {
+			route.host = r.host
 		}
 
 		// inherit flags if not set
@@ -334,19 +656,73 @@ func (r *router) compile() (routes []*route, ok bool) {
 			route.flags = r.flags
 		}
 
+		// inherit error handler
+		route.errorHandler = r.errorHandler
+
 		// bind events from router emitter
 		for eventType, listeners := range r.emitter {
 			for _, listener := range listeners {
 				route.emitter.On(eventType, listener)
 			}
 		}
+
+		// router middleware wraps route middleware
+		if len(r.middleware) > 0 {
+			route.middleware = append(append([]Middleware{}, r.middleware...), route.middleware...)
+		}
 		route.compile()
+
+		if r.name != "" {
+			route.name = r.name + "." + route.name
+		}
+		routes = append(routes, route)
 	}
 
+	r.compiled = true
 	ok = true
 	return
 }
 
+func (r *router) compileGroup(group *router) []*route {
+	group.prefix = joinPath(r.prefix, group.prefix)
+
+	if r.name != "" && group.name != "" {
+		group.name = r.name + "." + group.name
+	} else if group.name == "" {
+		group.name = r.name
+	}
+
+	// inherit host if not set
+	if group.host == "" {
+		group.host = r.host
+	}
+
+	// inherit flags if not set
+	if group.flags.Has(defaultState) {
+		group.flags = r.flags
+	}
+
+	// inherit error handler if not set
+	if group.errorHandler == nil {
+		group.errorHandler = r.errorHandler
+	}
+
+	// bind events from router emitter
+	for eventType, listeners := range r.emitter {
+		for _, listener := range listeners {
+			group.emitter.On(eventType, listener)
+		}
+	}
+
+	// router middleware wraps group middleware
+	if len(r.middleware) > 0 {
+		group.middleware = append(append([]Middleware{}, r.middleware...), group.middleware...)
+	}
+
+	routes, _ := group.compile()
+	return routes
+}
+
 func NewRouter(flags types.Flag) RouterInterface {
 	return &router{
 		emitter: make(events.Emitter),
@@ -357,10 +733,14 @@ func NewRouter(flags types.Flag) RouterInterface {
 
 // compiledRouter
 type compiledRouter struct {
-	routes   []*route
-	names    map[string]int
-	emitter  events.Emitter
-	compiled bool
+	routes     []*route
+	names      map[string]int
+	emitter    events.Emitter
+	middleware []Middleware
+	tree       *routeTree
+	compiled   bool
+
+	trailingSlash bool
 }
 
 func (r *compiledRouter) normalizeName(name string) string {
@@ -384,36 +764,19 @@ func (r *compiledRouter) addRouter(router RouterInterface) {
 	}
 }
 
-func (r *compiledRouter) allowedMethods(path string) (methods []string) {
+func (r *compiledRouter) allowedMethods(host, path string) (methods []string) {
 	methodSet := make(map[string]struct{})
-	for _, route := range r.routes {
-		if route.rePath == nil {
-			// check static path
-			if path != route.path {
-				continue
-			}
-		} else {
-			// check regex prefix
-			if !strings.HasPrefix(path, route.rePrefix) {
-				continue
-			}
-
-			// match expensive regex
-			if !route.rePath.MatchString(path) {
-				continue
-			}
-		}
-
+	for _, m := range r.tree.lookup(host, path) {
 		// can match any method
-		if len(route.methods) == 0 {
+		if len(m.route.methods) == 0 {
 			return make([]string, 0)
 		}
 
-		for _, method := range route.methods {
+		for _, method := range m.route.methods {
 			methodSet[method] = struct{}{}
 		}
 
-		if route.flags.Has(HandleOPTIONS) {
+		if m.route.flags.Has(HandleOPTIONS) {
 			methodSet[OPTIONS] = struct{}{}
 		}
 	}
@@ -428,63 +791,38 @@ func (r *compiledRouter) allowedMethods(path string) (methods []string) {
 	return
 }
 
-func (r *compiledRouter) match(method, path string) (*route, types.StringMap, types.Flag) {
-	var pathTrailingSlash = path[len(path)-1] == '/'
+func (r *compiledRouter) match(method, host, path string) (*route, types.StringMap, types.Flag) {
 	var methodNotAllowed bool
 	var flag types.Flag
 
-	for _, route := range r.routes {
-		var p = path
-		var match []string
-		var redirectTrailingSlash bool
-
-		// path is used unchanged for CONNECT requests
-		if method != CONNECT && route.flags.Has(RedirectTrailingSlash) {
-			// handle redirect for "/test" -> "/test/"
-			if route.trailingSlash && !pathTrailingSlash {
-				redirectTrailingSlash = true
-				p += "/"
-			}
-		}
-
-		if route.rePath == nil {
-			// check static path
-			if p != route.path {
-				continue // fail
-			}
-		} else {
-			// check regex prefix
-			if !strings.HasPrefix(p, route.rePrefix) {
-				continue // fail
-			}
+	matches := r.tree.lookup(host, path)
 
-			// match expensive regex
-			match = route.rePath.FindStringSubmatch(p)
-			if match == nil {
-				continue // fail
+	// path is used unchanged for CONNECT requests
+	if r.trailingSlash && method != CONNECT && path != "" && path[len(path)-1] != '/' {
+		// handle redirect for "/test" -> "/test/"
+		redirects := r.tree.lookup(host, path+"/")
+		for _, m := range redirects {
+			if m.route.trailingSlash && m.route.flags.Has(RedirectTrailingSlash) {
+				m.redirect = true
+				matches = append(matches, m)
 			}
 		}
+		if len(redirects) > 0 {
+			sort.Slice(matches, func(i, j int) bool {
+				return matches[i].index < matches[j].index
+			})
+		}
+	}
+
+	for _, m := range matches {
+		route := m.route
 
 		// match if any method allowed or method is explicitly defined
 		if len(route.methods) == 0 || helpers.IndexString(method, route.methods) != -1 {
-			if redirectTrailingSlash {
+			if m.redirect {
 				return nil, nil, RedirectTrailingSlash
 			}
-
-			// extract parameters from path
-			params := make(types.StringMap)
-			if match != nil {
-				subexpNames := route.rePath.SubexpNames()
-				for i, name := range subexpNames[1:] {
-					value := match[i+1]
-					if value == "" {
-						attr := route.params[name]
-						value = attr.DefaultValue
-					}
-					params.Set(name, value)
-				}
-			}
-			return route, params, flag
+			return route, m.params, flag
 		}
 
 		if method == OPTIONS && route.flags.Has(HandleOPTIONS) {
@@ -511,36 +849,76 @@ func (r *compiledRouter) find(name string) *route {
 	return nil
 }
 
-func (r *compiledRouter) url(name string, params types.StringMap) *url.URL {
+func (r *compiledRouter) url(name string, params types.Data) (*url.URL, error) {
 	route := r.find(name)
 	if route == nil {
-		panic(fmt.Sprintf(`gowl: cannot find route with name "%s"`, name))
+		return nil, &URLError{Route: name, Err: ErrRouteNotFound}
 	}
 
 	q := make(url.Values)
 	for name, value := range params {
-		q.Set(name, value)
+		v, err := route.formatParam(name, value)
+		if err != nil {
+			return nil, &URLError{Route: route.name, Param: name, Value: fmt.Sprint(value), Err: ErrInvalidParam}
+		}
+		q.Set(name, v)
+	}
+
+	var err error
+	host := route.host
+	if host != "" {
+		host = helpers.ReplaceAllStringSubmatchFunc(reRoutePathParams, host, func(m []string, _ int) string {
+			value, e := route.urlParam(name, m[1], params)
+			if err == nil {
+				err = e
+			}
+			q.Del(m[1])
+			return value
+		})
 	}
 
-	path := route.path
+	path, rawPath, lastIndex := route.path, "", 0
 	if strings.IndexByte(path, '{') != strings.IndexByte(path, '}') { // -1 != -1
-		path = helpers.ReplaceAllStringSubmatchFunc(reRoutePathParams, path, func(m []string, _ int) string {
-			value, ok := params[m[1]]
-			if !ok { // if parameter not given, try to pick default
-				if value = route.params[m[1]].DefaultValue; value == "" {
-					panic(fmt.Sprintf(`gowl: parameter "%s" is missing in path "%s"`, m[1], name))
-				}
-			} else if !route.params[m[1]].reRequirement.MatchString(value) {
-				panic(fmt.Sprintf(`gowl: parameter "%s" in path "%s" has invalid value "%s"`, m[1], name, value))
+		path = helpers.ReplaceAllStringSubmatchFunc(reRoutePathParams, path, func(m []string, i int) string {
+			value, e := route.urlParam(name, m[1], params)
+			if err == nil {
+				err = e
 			}
 			q.Del(m[1])
+
+			// escape value, but keep slashes of catch-all parameter
+			rawPath += escapePath(route.path[lastIndex:i])
+			if route.params[m[1]].catchAll {
+				rawPath += escapePath(value)
+			} else {
+				rawPath += url.PathEscape(value)
+			}
+			lastIndex = i + len(m[0])
+
 			return value
 		})
+		rawPath += escapePath(route.path[lastIndex:])
+	}
+	if err != nil {
+		return nil, err
 	}
+
 	return &url.URL{
+		Host:     host,
 		Path:     path,
+		RawPath:  rawPath,
 		RawQuery: q.Encode(),
+	}, nil
+}
+
+func (r *compiledRouter) chain(handler Handler, middleware ...Middleware) Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+	for i := len(r.middleware) - 1; i >= 0; i-- {
+		handler = r.middleware[i](handler)
 	}
+	return handler
 }
 
 func (r *compiledRouter) compile() bool {
@@ -555,8 +933,20 @@ func (r *compiledRouter) compile() bool {
 				route.emitter.On(eventType, listener)
 			}
 		}
+
+		// compose middleware in registration order
+		if route.handler != nil {
+			route.chain = r.chain(route.handler, route.middleware...)
+		}
+
+		if route.trailingSlash && route.flags.Has(RedirectTrailingSlash) {
+			r.trailingSlash = true
+		}
 	}
 
+	// build tree for matching requests
+	r.tree = newRouteTree(r.routes)
+
 	r.compiled = true
 	return true
 }
@@ -605,3 +995,33 @@ func assertPath(path string) {
 		i++
 	}
 }
+
+func getPrefixName(prefix string) string {
+	var names []string
+	for _, s := range strings.Split(prefix, "/") {
+		if s != "" && strings.IndexByte(s, '{') == -1 {
+			names = append(names, helpers.ToUnderscore(s))
+		}
+	}
+	return strings.Join(names, ".")
+}
+
+func joinPath(prefix, path string) string {
+	if n := len(prefix); n > 1 {
+		if prefix[n-1] == '/' {
+			return prefix + path[1:]
+		} else if path != "/" {
+			return prefix + path
+		}
+		return prefix
+	}
+	return path
+}
+
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
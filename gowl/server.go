@@ -1,10 +1,15 @@
 package gowl
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
 	"html/template"
+	"net"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/lokhman/gowl/events"
@@ -13,6 +18,7 @@ import (
 	"github.com/lokhman/gowl/templates"
 	"github.com/lokhman/gowl/types"
 	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
 )
 
 // ServerInterface
@@ -21,10 +27,17 @@ type ServerInterface interface {
 	NewRouter() RouterInterface
 	RegisterRouter(router RouterInterface, routers ...RouterInterface)
 	RegisterController(controller ControllerInterface, controllers ...ControllerInterface)
+	Use(middleware ...Middleware)
 	On(eventType events.EventType, listener func(event EventInterface))
 	LoadTemplates()
+	URL(name string, params types.Data) (*url.URL, error)
+	SignedURL(name string, params types.Data, expires time.Time) (*url.URL, error)
+	LintRoutes() []RouteIssue
 	Listen() error
+	ReloadCertificate() error
+	Shutdown(ctx context.Context) error
 	String() string
+	getListeners() map[string]net.Listener
 }
 
 // server
@@ -32,6 +45,19 @@ type server struct {
 	config    *Config
 	router    *compiledRouter
 	templates map[string]*template.Template
+
+	mu        sync.Mutex
+	http      *http.Server
+	listeners map[string]net.Listener
+	cert      *certificate
+	closed    bool
+
+	proxiesOnce sync.Once
+	proxies     []*net.IPNet
+
+	accessLogOnce sync.Once
+	accessLog     *accessLogger
+	accessLogErr  error
 }
 
 func (s *server) Config() *Config {
@@ -50,6 +76,10 @@ func (s *server) RegisterController(controller ControllerInterface, controllers
 	s.registerControllers(append([]ControllerInterface{controller}, controllers...))
 }
 
+func (s *server) Use(middleware ...Middleware) {
+	s.router.middleware = append(s.router.middleware, middleware...)
+}
+
 func (s *server) On(eventType events.EventType, listener func(event EventInterface)) {
 	s.router.emitter.On(eventType, listener)
 }
@@ -58,7 +88,10 @@ func (s *server) LoadTemplates() {
 	if s.templates != nil {
 		return
 	}
-	funcMap := make(template.FuncMap)
+	funcMap := template.FuncMap{
+		"url":  s.urlFunc,
+		"path": s.pathFunc,
+	}
 	for name, fn := range s.config.TemplateFunc {
 		funcMap[name] = fn
 	}
@@ -69,20 +102,168 @@ func (s *server) LoadTemplates() {
 	s.templates = t
 }
 
+func (s *server) URL(name string, params types.Data) (*url.URL, error) {
+	url, err := s.router.url(name, params)
+	if err != nil {
+		return nil, err
+	}
+
+	// route with host is always absolute
+	if url.Host != "" {
+		url.Scheme = "http"
+		if s.config.EnableTLS {
+			url.Scheme = "https"
+		}
+	}
+	return url, nil
+}
+
+func (s *server) LintRoutes() []RouteIssue {
+	return lintRoutes(s.router.routes)
+}
+
 func (s *server) Listen() error {
 	if !s.router.compile() {
 		return nil
 	}
 
-	server := &http.Server{
-		Addr:    s.config.Addr,
-		Handler: s,
+	// fail early on invalid configuration
+	s.trustedProxies()
+	if _, err := s.accessLogger(); err != nil {
+		return err
 	}
 
+	// shadowed routes are not allowed in strict mode
+	if s.config.StrictRouting {
+		for _, issue := range s.LintRoutes() {
+			if issue.Error {
+				panic(fmt.Sprintf("gowl: %s", issue.Message))
+			}
+		}
+	}
+
+	var tlsConfig *tls.Config
 	if s.config.EnableTLS {
-		return server.ListenAndServeTLS(s.config.CertFile, s.config.KeyFile)
+		cert, err := newCertificate(s.config.CertFile, s.config.KeyFile)
+		if err != nil {
+			return err
+		}
+		if tlsConfig, err = newTLSConfig(s.config, cert); err != nil {
+			return err
+		}
+
+		// reload certificate when files change
+		if interval := time.Duration(s.config.CertReloadInterval); interval > 0 {
+			done := make(chan struct{})
+			defer close(done)
+			go cert.watch(interval, done)
+		}
+
+		s.mu.Lock()
+		s.cert = cert
+		s.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	if s.closed { // shut down before started
+		s.mu.Unlock()
+		return nil
+	}
+	s.http = &http.Server{
+		Addr:              s.config.ListenAddrs()[0],
+		Handler:           s,
+		ReadTimeout:       time.Duration(s.config.ReadTimeout),
+		ReadHeaderTimeout: time.Duration(s.config.ReadHeaderTimeout),
+		WriteTimeout:      time.Duration(s.config.WriteTimeout),
+		IdleTimeout:       time.Duration(s.config.IdleTimeout),
+		MaxHeaderBytes:    s.config.MaxHeaderBytes,
+		TLSConfig:         tlsConfig,
+		Protocols:         s.config.protocols(),
+		HTTP2: &http.HTTP2Config{
+			MaxConcurrentStreams: s.config.HTTP2MaxConcurrentStreams,
+			MaxReadFrameSize:     s.config.HTTP2MaxFrameSize,
+		},
+	}
+	s.mu.Unlock()
+
+	s.emit(EventStarting)
+
+	listeners := make(map[string]net.Listener)
+	for _, addr := range s.config.ListenAddrs() {
+		ln, err := listen(addr)
+		if err != nil {
+			for _, ln := range listeners {
+				ln.Close()
+			}
+			return err
+		}
+		listeners[addr] = ln
+	}
+
+	s.mu.Lock()
+	s.listeners = listeners
+	s.mu.Unlock()
+
+	s.emit(EventStarted)
+
+	var stack errgroup.Group
+	for _, ln := range listeners {
+		ln := ln
+		stack.Go(func() (err error) {
+			if s.config.EnableTLS {
+				err = s.http.ServeTLS(ln, "", "")
+			} else {
+				err = s.http.Serve(ln)
+			}
+			if err == http.ErrServerClosed {
+				return nil
+			}
+
+			// stop serving other listeners on failure
+			s.http.Close()
+			return err
+		})
+	}
+	return stack.Wait()
+}
+
+func (s *server) ReloadCertificate() error {
+	s.mu.Lock()
+	cert := s.cert
+	s.mu.Unlock()
+
+	if cert == nil {
+		return nil
+	}
+	return cert.load()
+}
+
+func (s *server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	server := s.http
+	s.closed = true
+	s.mu.Unlock()
+
+	if server == nil {
+		return nil
+	}
+
+	s.emit(EventStopping)
+
+	// close remaining connections if not drained in time
+	err := server.Shutdown(ctx)
+	if err != nil {
+		server.Close()
 	}
-	return server.ListenAndServe()
+
+	s.emit(EventStopped)
+	return err
+}
+
+func (s *server) getListeners() map[string]net.Listener {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.listeners
 }
 
 func (s *server) String() string {
@@ -122,10 +303,41 @@ func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	var start = time.Now()
 	var path = r.URL.Path
 	var handler Handler
+	var err error
 
-	var request = &Request{Request: r, server: s}
+	// capture status code and size for access log
+	w = newResponseWriter(w)
+
+	var request = &Request{Request: r, server: s, writer: w}
 	var response ResponseInterface
 
+	// protect all handlers including NotFound and MethodNotAllowed
+	defer s.recoverPanic(w, request, start)
+
+	if s.config.ServerName != "" {
+		w.Header().Set("Server", s.config.ServerName)
+	}
+
+	// correlate request with logs
+	if header := s.config.RequestIDHeader; header != "" {
+		request.id = r.Header.Get(header)
+		if !isValidRequestID(request.id) {
+			request.id = newRequestID()
+		}
+		w.Header().Set(header, request.id)
+	}
+
+	// resolve client address, scheme and host behind trusted proxies
+	var scheme string
+	request.clientIP, scheme, r.Host = s.resolveForwarded(r)
+
+	// reject unknown hosts to prevent host header injection
+	if !s.isAllowedHost(r.Host) {
+		response = s.error(request, http.StatusBadRequest, fmt.Errorf(`gowl: host "%s" is not allowed`, r.Host))
+		s.serve(w, request, response, start)
+		return
+	}
+
 	// redirect request to lowercase path if configured
 	if s.config.RedirectUpperCasePath && helpers.IndexUpper(path) != -1 {
 		response = s.redirect(request, strings.ToLower(path))
@@ -134,21 +346,22 @@ func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// match request by method and path
-	route, params, flag := s.router.match(r.Method, path)
+	route, params, flag := s.router.match(r.Method, r.Host, path)
+	request.route = route
 
 	switch flag {
 	case HandleOPTIONS:
 		// handle OPTIONS automatically
-		s.setAllowHeaderForPath(w, path)
+		s.setAllowHeader(w, r.Host, path)
 		response = NewResponse(http.StatusOK, nil)
 		s.serve(w, request, response, start)
 		return
 	case HandleMethodNotAllowed:
-		s.setAllowHeaderForPath(w, path)
+		s.setAllowHeader(w, r.Host, path)
 
 		// if handler not configured, return plain HTTP error
 		if handler = s.config.MethodNotAllowedHandler; handler == nil {
-			response = s.error(http.StatusMethodNotAllowed, "")
+			response = s.error(request, http.StatusMethodNotAllowed, nil)
 			s.serve(w, request, response, start)
 			return
 		}
@@ -160,86 +373,92 @@ func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if route != nil {
-		handler = route.handler
+		handler = route.chain
 	} else {
 		// if handler not configured, return plain HTTP error
-		if handler = s.config.NotFoundHandler; handler == nil {
-			response = s.error(http.StatusNotFound, "")
-			s.serve(w, request, response, start)
-			return
+		if handler == nil {
+			if handler = s.config.NotFoundHandler; handler == nil {
+				response = s.error(request, http.StatusNotFound, nil)
+				s.serve(w, request, response, start)
+				return
+			}
 		}
+		handler = s.router.chain(handler)
 	}
 
 	// if handler is still not defined
 	if handler == nil {
-		response = s.error(http.StatusNotImplemented, "")
+		response = s.error(request, http.StatusNotImplemented, nil)
 		s.serve(w, request, response, start)
 		return
 	}
 
-	// add special parameters
-	params.Set(":route", route.name)
-	params.Set(":path", route.path)
+	// events are emitted by route or by server if not matched
+	emitter := s.router.emitter
+	if route != nil {
+		emitter = route.emitter
+
+		// convert typed parameters
+		if request.values, err = route.convertParams(params); err != nil {
+			response = s.error(request, http.StatusNotFound, err)
+			s.serve(w, request, response, start)
+			return
+		}
+
+		// add special parameters
+		params.Set(":route", route.name)
+		params.Set(":path", route.path)
+	}
 	request.params = params
 
-	// resolve request scheme
-	if scheme := r.Header.Get("X-Scheme"); scheme != "" {
-		request.URL.Scheme = strings.ToLower(scheme)
-	} else if s.config.EnableTLS {
-		request.URL.Scheme = "https"
-	} else {
-		request.URL.Scheme = "http"
+	// limit request body size
+	var body *limitedBody
+	if route != nil && route.maxBodySize > 0 {
+		if r.ContentLength > route.maxBodySize {
+			response = s.error(request, http.StatusRequestEntityTooLarge, nil)
+			s.serve(w, request, response, start)
+			return
+		}
+		// net/http closes the connection only if it gets its own writer
+		body = &limitedBody{ReadCloser: http.MaxBytesReader(newResponseWriter(w).Unwrap(), r.Body, route.maxBodySize)}
+		r.Body = body
 	}
 
-	// TODO: validate host
+	// set resolved request scheme
+	request.URL.Scheme = scheme
+
 	// specify host in URL
 	if r.URL.Host == "" {
 		r.URL.Host = r.Host
 	}
 
-	// recover
-	defer func() {
-		if err := recover(); err != nil {
-			var e error
-			switch err := err.(type) {
-			case string:
-				e = errors.New(err)
-			case error:
-				e = errors.Wrap(err, "gowl")
-			default:
-				e = errors.New(fmt.Sprintf("%+v", err))
-			}
-
-			// emit "panic" events
-			if route.emitter.HasListeners(EventPanic) {
-				event := &PanicEvent{error: e}
-				route.emitter.Emit(EventPanic, event)
-			}
-
-			// display error 500 with stack trace
-			stack := getMainStackTrace(e.(stackTracer).StackTrace())
-			debug := fmt.Sprintf("%s\n%+v", err, stack)
-			response = s.error(http.StatusInternalServerError, debug)
-			s.serve(w, request, response, start)
-		}
-	}()
-
 	// emit "request" events
-	if route.emitter.HasListeners(EventRequest) {
+	if emitter.HasListeners(EventRequest) {
 		event := &RequestEvent{request: request}
-		route.emitter.Emit(EventRequest, event)
+		emitter.Emit(EventRequest, event)
 		response = event.response
 	}
 
 	// handle request
 	if response == nil {
-		response = handler(request)
+		if timeout := s.handlerTimeout(route); timeout > 0 {
+			var cancel context.CancelFunc
+			response, cancel = s.handleTimeout(request, handler, timeout)
+			defer cancel()
+		} else {
+			response = handler(request)
+		}
+	}
+
+	// handler read more than allowed
+	if body != nil && body.exceeded {
+		response = s.error(request, http.StatusRequestEntityTooLarge, nil)
 	}
 
 	// emit "response" events
-	if route.emitter.HasListeners(EventResponse) {
+	if emitter.HasListeners(EventResponse) {
 		event := &ResponseEvent{request: request, response: response}
-		route.emitter.Emit(EventResponse, event)
+		emitter.Emit(EventResponse, event)
 		response = event.response
 	}
 
@@ -253,11 +472,7 @@ func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *server) serve(w http.ResponseWriter, request *Request, response ResponseInterface, start time.Time) {
-	defer func() { _ = recover() }()
-
-	if s.config.ServerName != "" {
-		w.Header().Set("Server", s.config.ServerName)
-	}
+	defer s.recoverPanic(w, request, start)
 
 	statusCode := response.StatusCode()
 	if _, ok := response.(ResponseWriterInterface); !ok {
@@ -269,6 +484,10 @@ func (s *server) serve(w http.ResponseWriter, request *Request, response Respons
 		Error.Print(err)
 	}
 
+	if l, _ := s.accessLogger(); l != nil {
+		l.log(newAccessLogEntry(request, newResponseWriter(w), statusCode, start))
+	}
+
 	buf := new(strings.Builder)
 	fmt.Fprintf(buf, "%3d %s %s", statusCode, request.Method, request.URL.Path)
 	if name := request.Param(":route"); name != "" {
@@ -280,6 +499,20 @@ func (s *server) serve(w http.ResponseWriter, request *Request, response Respons
 	Debug.Print(buf.String())
 }
 
+func (s *server) handlerTimeout(route *route) time.Duration {
+	if route != nil && route.timeout > 0 {
+		return route.timeout
+	}
+	return time.Duration(s.config.HandlerTimeout)
+}
+
+func (s *server) accessLogger() (*accessLogger, error) {
+	s.accessLogOnce.Do(func() {
+		s.accessLog, s.accessLogErr = newAccessLogger(s.config)
+	})
+	return s.accessLog, s.accessLogErr
+}
+
 func (_ *server) redirect(request *Request, url string) ResponseInterface {
 	statusCode := http.StatusMovedPermanently
 	if request.Method != GET {
@@ -288,16 +521,123 @@ func (_ *server) redirect(request *Request, url string) ResponseInterface {
 	return RedirectResponse(request, statusCode, url)
 }
 
-func (_ *server) error(statusCode int, debug string) ResponseInterface {
-	if !*_debug && debug != "" {
-		Error.Print(debug)
-		debug = ""
+// recoverPanic converts panic in handler or while writing response to error
+// response, or aborts connection if response is already partially written.
+func (s *server) recoverPanic(w http.ResponseWriter, request *Request, start time.Time) {
+	value := recover()
+	if value == nil {
+		return
+	}
+	if value == http.ErrAbortHandler {
+		panic(value)
+	}
+
+	p, ok := value.(*panicInfo)
+	if !ok {
+		p = newPanicInfo(value, s.config.StackTraceDepth)
+	}
+
+	// emit "panic" events
+	emitter := s.router.emitter
+	if request.route != nil {
+		emitter = request.route.emitter
+	}
+	if emitter.HasListeners(EventPanic) {
+		event := &PanicEvent{request: request, value: p.value, error: p.error(), stack: p.stack}
+		emitter.Emit(EventPanic, event)
+	}
+
+	err := errors.New(p.String())
+	if rw := newResponseWriter(w); request.panicked || rw.statusCode != 0 {
+		// status line is already sent, so client must see broken response
+		if id := request.ID(); id != "" {
+			Error.Printf("[%s] gowl: response aborted: %s", id, err)
+		} else {
+			Error.Printf("gowl: response aborted: %s", err)
+		}
+		if l, _ := s.accessLogger(); l != nil {
+			l.log(newAccessLogEntry(request, rw, rw.statusCode, start))
+		}
+		panic(http.ErrAbortHandler)
+	}
+
+	request.panicked = true
+	s.serve(w, request, s.error(request, http.StatusInternalServerError, err), start)
+}
+
+// handleError maps error returned by handler to response.
+func (s *server) handleError(request *Request, err error) ResponseInterface {
+	if route := request.route; route != nil && route.emitter.HasListeners(EventError) {
+		event := &ErrorEvent{request: request, error: err}
+		route.emitter.Emit(EventError, event)
+		if event.response != nil {
+			return event.response
+		}
+	}
+
+	statusCode := http.StatusInternalServerError
+	var e *HTTPError
+	if errors.As(err, &e) && e.StatusCode != 0 {
+		statusCode = e.StatusCode
+	}
+	return s.error(request, statusCode, err)
+}
+
+func (s *server) error(request *Request, statusCode int, err error) ResponseInterface {
+	if err != nil && !*_debug {
+		if id := request.ID(); id != "" {
+			Error.Printf("[%s] %s", id, err)
+		} else {
+			Error.Print(err)
+		}
+	}
+
+	handler := s.config.ErrorHandler
+	if request.route != nil && request.route.errorHandler != nil {
+		handler = request.route.errorHandler
+	}
+	if handler != nil {
+		if response := handler(request, statusCode, err); response != nil {
+			return response
+		}
+	}
+	return DefaultErrorHandler(request, statusCode, err)
+}
+
+func (s *server) emit(eventType events.EventType) {
+	if s.router.emitter.HasListeners(eventType) {
+		event := &ServerEvent{server: s}
+		s.router.emitter.Emit(eventType, event)
 	}
-	return ErrorResponse(statusCode, debug)
 }
 
-func (s *server) setAllowHeaderForPath(w http.ResponseWriter, path string) {
-	if allow := s.router.allowedMethods(path); len(allow) > 0 {
+func (s *server) urlFunc(name string, params ...interface{}) (string, error) {
+	data, err := getTemplateURLParams(params)
+	if err != nil {
+		return "", err
+	}
+	url, err := s.URL(name, data)
+	if err != nil {
+		return "", err
+	}
+	return url.String(), nil
+}
+
+func (s *server) pathFunc(name string, params ...interface{}) (string, error) {
+	data, err := getTemplateURLParams(params)
+	if err != nil {
+		return "", err
+	}
+	url, err := s.router.url(name, data)
+	if err != nil {
+		return "", err
+	}
+	url.Host = ""
+	return url.String(), nil
+}
+
+func (s *server) setAllowHeader(w http.ResponseWriter, host, path string) {
+	if allow := s.router.allowedMethods(host, path); len(allow) > 0 {
 		w.Header().Set("Allow", strings.Join(allow, ", "))
 	}
 }
@@ -342,3 +682,33 @@ func NewServer(config *Config) ServerInterface {
 		router: newCompiledRouter(),
 	}
 }
+
+// ...
+func getTemplateURLParams(params []interface{}) (types.Data, error) {
+	if len(params) == 1 {
+		switch p := params[0].(type) {
+		case types.Data:
+			return p, nil
+		case map[string]interface{}:
+			return p, nil
+		case types.StringMap:
+			data := make(types.Data, len(p))
+			for key, value := range p {
+				data[key] = value
+			}
+			return data, nil
+		}
+	}
+	if len(params)%2 != 0 {
+		return nil, errors.New("gowl: URL parameters must be given in key and value pairs")
+	}
+	data := make(types.Data, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		key, ok := params[i].(string)
+		if !ok {
+			return nil, errors.Errorf("gowl: URL parameter key %v must be a string", params[i])
+		}
+		data[key] = params[i+1]
+	}
+	return data, nil
+}
@@ -0,0 +1,128 @@
+package gowl
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lokhman/gowl/httputil"
+	"github.com/lokhman/gowl/types"
+	"github.com/pkg/errors"
+)
+
+const (
+	SignatureParam = "signature"
+	ExpiresParam   = "expires"
+)
+
+var (
+	ErrMissingSigningKey = errors.New("gowl: signing key is not configured")
+	ErrInvalidSignature  = errors.New("gowl: URL signature is invalid")
+	ErrExpiredSignature  = errors.New("gowl: URL signature has expired")
+)
+
+// VerifySignature is a middleware that rejects requests with tampered or
+// expired URLs created with SignedURL. URLs signed for a host are accepted
+// only on the same host.
+func VerifySignature(next Handler) Handler {
+	return func(r *Request) ResponseInterface {
+		if err := verifyURL(r.server.config.SigningKey, r.Host, r.URL); err != nil {
+			return r.server.error(r, http.StatusForbidden, err)
+		}
+		return next(r)
+	}
+}
+
+func (s *server) SignedURL(name string, params types.Data, expires time.Time) (*url.URL, error) {
+	url, err := s.URL(name, params)
+	if err != nil {
+		return nil, err
+	}
+	if err = signURL(s.config.SigningKey, url.Host, url, expires); err != nil {
+		return nil, err
+	}
+	return url, nil
+}
+
+func (r *Request) BuildSignedURL(name string, params types.Data, expires time.Time, absolute bool) (*url.URL, error) {
+	url, err := r.BuildURL(name, params, absolute)
+	if err != nil {
+		return nil, err
+	}
+
+	// relative URL is signed for the current host
+	host := url.Host
+	if host == "" {
+		host = r.Host
+	}
+	if err = signURL(r.server.config.SigningKey, host, url, expires); err != nil {
+		return nil, err
+	}
+	return url, nil
+}
+
+// ...
+func signURL(key, host string, u *url.URL, expires time.Time) error {
+	if key == "" {
+		return ErrMissingSigningKey
+	}
+
+	q := u.Query()
+	q.Del(SignatureParam)
+	q.Del(ExpiresParam)
+	if !expires.IsZero() {
+		q.Set(ExpiresParam, strconv.FormatInt(expires.Unix(), 10))
+	}
+	q.Set(SignatureParam, getURLSignature(key, host, u.EscapedPath(), q))
+	u.RawQuery = q.Encode()
+	return nil
+}
+
+func verifyURL(key, host string, u *url.URL) error {
+	if key == "" {
+		return ErrMissingSigningKey
+	}
+
+	q := u.Query()
+	signature := q.Get(SignatureParam)
+	if signature == "" {
+		return ErrInvalidSignature
+	}
+	// URL may be signed without host if its route has none
+	if !hmac.Equal([]byte(signature), []byte(getURLSignature(key, host, u.EscapedPath(), q))) &&
+		!hmac.Equal([]byte(signature), []byte(getURLSignature(key, "", u.EscapedPath(), q))) {
+		return ErrInvalidSignature
+	}
+
+	if expires := q.Get(ExpiresParam); expires != "" {
+		ts, err := strconv.ParseInt(expires, 10, 64)
+		if err != nil {
+			return ErrInvalidSignature
+		}
+		if time.Now().Unix() > ts {
+			return ErrExpiredSignature
+		}
+	}
+	return nil
+}
+
+func getURLSignature(key, host, path string, q url.Values) string {
+	values := make(url.Values, len(q))
+	for k, v := range q {
+		if k != SignatureParam {
+			values[k] = v
+		}
+	}
+
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(strings.ToLower(httputil.StripPort(host))))
+	mac.Write([]byte(path))
+	mac.Write([]byte{'?'})
+	mac.Write([]byte(values.Encode()))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,48 @@
+package gowl
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestSignURLHost(t *testing.T) {
+	const key = "secret"
+
+	u := &url.URL{Path: "/reset", RawQuery: "user=1"}
+	if err := signURL(key, "acme.example.com", u, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		host string
+		err  error
+	}{
+		{"acme.example.com", nil},
+		{"ACME.example.com:8080", nil},
+		{"evil.example.com", ErrInvalidSignature},
+		{"", ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		if err := verifyURL(key, tt.host, u); err != tt.err {
+			t.Errorf("verifyURL(%q) = %v, want %v", tt.host, err, tt.err)
+		}
+	}
+
+	// URL of a route without host is valid on any host
+	u = &url.URL{Path: "/download", RawQuery: "file=1"}
+	if err := signURL(key, "", u, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyURL(key, "any.example.com", u); err != nil {
+		t.Errorf("verifyURL(hostless) = %v, want <nil>", err)
+	}
+
+	// tampered query is rejected
+	q := u.Query()
+	q.Set("file", "2")
+	u.RawQuery = q.Encode()
+	if err := verifyURL(key, "any.example.com", u); err != ErrInvalidSignature {
+		t.Errorf("verifyURL(tampered) = %v, want %v", err, ErrInvalidSignature)
+	}
+}
@@ -0,0 +1,133 @@
+package gowl
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/lokhman/gowl/httputil"
+	"github.com/lokhman/gowl/types"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrHandlerTimeout  = errors.New("gowl: handler timeout exceeded")
+	ErrRequestCanceled = errors.New("gowl: request canceled")
+)
+
+// timeoutWriter
+type timeoutWriter struct {
+	w      http.ResponseWriter
+	header http.Header
+
+	mu          sync.Mutex
+	timedOut    bool
+	wroteHeader bool
+	statusCode  int
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(statusCode int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+	tw.writeHeader(statusCode)
+}
+
+func (tw *timeoutWriter) writeHeader(statusCode int) {
+	tw.wroteHeader = true
+	tw.statusCode = statusCode
+	httputil.CopyHeader(tw.w.Header(), tw.header)
+	tw.w.WriteHeader(statusCode)
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if !tw.wroteHeader {
+		tw.writeHeader(http.StatusOK)
+	}
+	return tw.w.Write(b)
+}
+
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if f, ok := tw.w.(http.Flusher); ok && !tw.timedOut {
+		f.Flush()
+	}
+}
+
+// timeout stops handler from writing and reports if response was started.
+func (tw *timeoutWriter) timeout() (statusCode int, started bool) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.timedOut = true
+	return tw.statusCode, tw.wroteHeader
+}
+
+// handleTimeout runs handler with context deadline and returns 503 error
+// response if the handler does not finish in time. Returned cancel function
+// must be called after the response is written, as streaming responses may
+// still use the request context.
+func (s *server) handleTimeout(request *Request, handler Handler, timeout time.Duration) (ResponseInterface, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(request.Context(), timeout)
+
+	tw := &timeoutWriter{w: request.writer, header: make(http.Header)}
+	r := request.WithContext(ctx)
+	r.writer = tw
+
+	// handler may still run after timeout, so it gets its own copy of data
+	r.Data = make(types.Data, len(request.Data))
+	for key, value := range request.Data {
+		r.Data[key] = value
+	}
+
+	done := make(chan ResponseInterface, 1)
+	panicked := make(chan interface{}, 1)
+	go func() {
+		defer func() {
+			// keep stack of the handler goroutine
+			if value := recover(); value == http.ErrAbortHandler {
+				panicked <- value
+			} else if value != nil {
+				panicked <- newPanicInfo(value, s.config.StackTraceDepth)
+			}
+		}()
+		done <- handler(r)
+	}()
+
+	select {
+	case response := <-done:
+		tw.mu.Lock()
+		defer tw.mu.Unlock()
+		httputil.CopyHeader(request.writer.Header(), tw.header)
+		request.Data = r.Data
+		return response, cancel
+	case value := <-panicked:
+		cancel()
+		panic(value)
+	case <-ctx.Done():
+		cancel()
+		statusCode, started := tw.timeout()
+		if started {
+			// response is already partially written
+			return &writtenResponse{statusCode: statusCode, header: make(http.Header)}, cancel
+		}
+		if ctx.Err() == context.DeadlineExceeded {
+			return s.error(request, http.StatusServiceUnavailable, ErrHandlerTimeout), cancel
+		}
+
+		// client has gone away
+		return s.error(request, http.StatusServiceUnavailable, ErrRequestCanceled), cancel
+	}
+}
@@ -0,0 +1,84 @@
+package gowl
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lokhman/gowl/types"
+)
+
+func newTimeoutTestServer(fn func(r RouterInterface)) *server {
+	config := NewConfig()
+	config.HandlerTimeout = types.Duration(50 * time.Millisecond)
+	s := NewServer(config).(*server)
+	router := NewRouter(0)
+	fn(router)
+	s.RegisterRouter(router)
+	return s
+}
+
+func TestHandleTimeoutStreamContext(t *testing.T) {
+	s := newTimeoutTestServer(func(r RouterInterface) {
+		r.GET("/stream", func(r *Request) ResponseInterface {
+			r.Data.Set("handler", true)
+			return StreamResponse(http.StatusOK, func(w io.Writer) error {
+				// context is alive until the response is written
+				if err := r.Context().Err(); err != nil {
+					_, err = io.WriteString(w, err.Error())
+					return err
+				}
+				_, err := io.WriteString(w, "ok")
+				return err
+			})
+		})
+	})
+
+	var data bool
+	s.On(EventResponse, func(event EventInterface) {
+		data = event.(*ResponseEvent).Request().Data.GetBool("handler")
+	})
+	s.router.compile()
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(GET, "/stream", nil))
+	if body := w.Body.String(); body != "ok" {
+		t.Errorf("body = %q, want %q", body, "ok")
+	}
+	if !data {
+		t.Error("data set by handler is not passed to response event")
+	}
+}
+
+func TestHandleTimeoutData(t *testing.T) {
+	release := make(chan struct{})
+	finished := make(chan struct{})
+	s := newTimeoutTestServer(func(r RouterInterface) {
+		r.GET("/slow", func(r *Request) ResponseInterface {
+			defer close(finished)
+			<-release
+			r.Data.Set("late", true) // must not race with response listeners
+			return EmptyResponse()
+		})
+	})
+
+	var late bool
+	s.On(EventResponse, func(event EventInterface) {
+		late = event.(*ResponseEvent).Request().Data.GetBool("late")
+	})
+	s.router.compile()
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(GET, "/slow", nil))
+	close(release)
+	<-finished
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+	if late {
+		t.Error("data set after timeout is visible to response event")
+	}
+}
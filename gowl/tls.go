@@ -0,0 +1,151 @@
+package gowl
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var tlsClientAuthTypes = map[string]tls.ClientAuthType{
+	"none":               tls.NoClientCert,
+	"request":            tls.RequestClientCert,
+	"require":            tls.RequireAnyClientCert,
+	"verify_if_given":    tls.VerifyClientCertIfGiven,
+	"require_and_verify": tls.RequireAndVerifyClientCert,
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// certificate
+type certificate struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+func (c *certificate) load() error {
+	modTime := c.lastModified()
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return errors.Wrap(err, "gowl: cannot load certificate")
+	}
+
+	c.mu.Lock()
+	c.cert = &cert
+	c.modTime = modTime
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *certificate) changed() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.lastModified().After(c.modTime)
+}
+
+func (c *certificate) lastModified() (t time.Time) {
+	for _, name := range []string{c.certFile, c.keyFile} {
+		if fi, err := os.Stat(name); err == nil && fi.ModTime().After(t) {
+			t = fi.ModTime()
+		}
+	}
+	return
+}
+
+func (c *certificate) get(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cert, nil
+}
+
+func (c *certificate) watch(interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if !c.changed() {
+				continue
+			}
+			if err := c.load(); err != nil {
+				Error.Print(err)
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+func newCertificate(certFile, keyFile string) (*certificate, error) {
+	c := &certificate{certFile: certFile, keyFile: keyFile}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func newTLSConfig(config *Config, cert *certificate) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		GetCertificate: cert.get,
+	}
+
+	if config.MinTLSVersion != "" {
+		version, ok := tlsVersions[config.MinTLSVersion]
+		if !ok {
+			return nil, fmt.Errorf(`gowl: unknown TLS version "%s"`, config.MinTLSVersion)
+		}
+		tlsConfig.MinVersion = version
+	}
+
+	if len(config.CipherSuites) > 0 {
+		suites := make(map[string]uint16)
+		for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+			suites[suite.Name] = suite.ID
+		}
+		for _, name := range config.CipherSuites {
+			id, ok := suites[strings.ToUpper(name)]
+			if !ok {
+				return nil, fmt.Errorf(`gowl: unknown cipher suite "%s"`, name)
+			}
+			tlsConfig.CipherSuites = append(tlsConfig.CipherSuites, id)
+		}
+	}
+
+	if config.ClientCAFile != "" {
+		pem, err := ioutil.ReadFile(config.ClientCAFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "gowl: cannot load client CA")
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf(`gowl: no certificates found in "%s"`, config.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	if config.ClientAuth != "" {
+		auth, ok := tlsClientAuthTypes[config.ClientAuth]
+		if !ok {
+			return nil, fmt.Errorf(`gowl: unknown client auth mode "%s"`, config.ClientAuth)
+		}
+		tlsConfig.ClientAuth = auth
+	}
+	return tlsConfig, nil
+}
@@ -0,0 +1,214 @@
+package gowl
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/lokhman/gowl/types"
+)
+
+// routeMatch
+type routeMatch struct {
+	index    int
+	route    *route
+	params   types.StringMap
+	redirect bool
+}
+
+// routeSegment
+type routeSegment struct {
+	key  string
+	name string         // set for a single parameter with default requirement
+	re   *regexp.Regexp // set for other dynamic segments
+	node *routeNode
+}
+
+// routeNode
+type routeNode struct {
+	static  map[string]*routeNode
+	dynamic []*routeSegment
+	leaves  []int // routes that end at this node
+	rests   []int // routes with catch-all parameter that match the rest of the path
+	tails   []int // routes that match the rest of the path with regex
+}
+
+func (n *routeNode) staticChild(segment string) *routeNode {
+	if n.static == nil {
+		n.static = make(map[string]*routeNode)
+	}
+	child, ok := n.static[segment]
+	if !ok {
+		child = new(routeNode)
+		n.static[segment] = child
+	}
+	return child
+}
+
+func (n *routeNode) dynamicChild(key, name string, re *regexp.Regexp) *routeNode {
+	for _, s := range n.dynamic {
+		if s.key == key {
+			return s.node
+		}
+	}
+	s := &routeSegment{key: key, name: name, re: re, node: new(routeNode)}
+	n.dynamic = append(n.dynamic, s)
+	return s.node
+}
+
+// routeTree
+type routeTree struct {
+	routes []*route
+	root   *routeNode
+}
+
+func (t *routeTree) insert(index int) {
+	route := t.routes[index]
+	node := t.root
+
+	for _, segment := range strings.Split(route.path[1:], "/") {
+		m := reRoutePathParams.FindAllStringSubmatchIndex(segment, -1)
+		if m == nil {
+			node = node.staticChild(segment)
+			continue
+		}
+
+		// catch-all parameter is always the last segment
+		if len(m) == 1 && m[0][4] != -1 {
+			node.rests = append(node.rests, index)
+
+			// mounted handler also serves its prefix
+			if route.mount {
+				node.leaves = append(node.leaves, index)
+			}
+			return
+		}
+
+		// parameters that can span segments are matched with path regex
+		for _, v := range m {
+			if route.params[segment[v[2]:v[3]]].spanSegments {
+				node.tails = append(node.tails, index)
+				return
+			}
+		}
+
+		// single parameter with default requirement does not need regex
+		if len(m) == 1 && m[0][0] == 0 && m[0][1] == len(segment) {
+			name := segment[m[0][2]:m[0][3]]
+			if attr := route.params[name]; attr.Requirement == RouteParamRequirement {
+				node = node.dynamicChild(segment, name, nil)
+				continue
+			}
+		}
+
+		lastIndex, expr := 0, "^"
+		for _, v := range m {
+			name := segment[v[2]:v[3]]
+			expr += regexp.QuoteMeta(segment[lastIndex:v[0]])
+			expr += "(?P<" + name + ">" + route.params[name].Requirement + ")"
+			lastIndex = v[1]
+		}
+		expr += regexp.QuoteMeta(segment[lastIndex:]) + "$"
+		node = node.dynamicChild(expr, "", regexp.MustCompile(expr))
+	}
+	node.leaves = append(node.leaves, index)
+}
+
+func (t *routeTree) lookup(host, path string) []routeMatch {
+	matches := make([]routeMatch, 0)
+	if path == "" || path[0] != '/' {
+		return matches
+	}
+	t.walk(t.root, path, strings.Split(path[1:], "/"), nil, &matches)
+
+	// filter routes by host
+	n := 0
+	for _, m := range matches {
+		if m.route.matchHost(host, m.params) {
+			matches[n] = m
+			n++
+		}
+	}
+	matches = matches[:n]
+
+	// keep first-registered-wins semantics
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].index < matches[j].index
+	})
+	return matches
+}
+
+func (t *routeTree) walk(n *routeNode, path string, segments []string, values []string, matches *[]routeMatch) {
+	for _, index := range n.tails {
+		route := t.routes[index]
+		if match := route.rePath.FindStringSubmatch(path); match != nil {
+			params := make(types.StringMap)
+			for i, name := range route.rePath.SubexpNames()[1:] {
+				route.setParam(params, name, match[i+1])
+			}
+			*matches = append(*matches, routeMatch{index: index, route: route, params: params})
+		}
+	}
+
+	if len(segments) > 0 {
+		for _, index := range n.rests {
+			route := t.routes[index]
+			attr := route.params[route.catchAll]
+			rest := strings.Join(segments, "/")
+			if attr.Requirement != RouteCatchAllRequirement && !attr.reRequirement.MatchString(rest) {
+				continue
+			}
+			params := newMatchParams(route, append(values, route.catchAll, rest))
+			*matches = append(*matches, routeMatch{index: index, route: route, params: params})
+		}
+	}
+
+	if len(segments) == 0 {
+		for _, index := range n.leaves {
+			route := t.routes[index]
+			params := newMatchParams(route, values)
+			*matches = append(*matches, routeMatch{index: index, route: route, params: params})
+		}
+		return
+	}
+
+	segment, segments := segments[0], segments[1:]
+	if child, ok := n.static[segment]; ok {
+		t.walk(child, path, segments, values, matches)
+	}
+
+	for _, s := range n.dynamic {
+		if s.re == nil {
+			if segment != "" {
+				t.walk(s.node, path, segments, append(values, s.name, segment), matches)
+			}
+			continue
+		}
+		if match := s.re.FindStringSubmatch(segment); match != nil {
+			v := values
+			for i, name := range s.re.SubexpNames()[1:] {
+				v = append(v, name, match[i+1])
+			}
+			t.walk(s.node, path, segments, v, matches)
+		}
+	}
+}
+
+func newMatchParams(route *route, values []string) types.StringMap {
+	params := make(types.StringMap)
+	for i := 0; i < len(values); i += 2 {
+		route.setParam(params, values[i], values[i+1])
+	}
+	return params
+}
+
+func newRouteTree(routes []*route) *routeTree {
+	t := &routeTree{
+		routes: routes,
+		root:   new(routeNode),
+	}
+	for i := range routes {
+		t.insert(i)
+	}
+	return t
+}
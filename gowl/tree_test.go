@@ -0,0 +1,237 @@
+package gowl
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/lokhman/gowl/helpers"
+	"github.com/lokhman/gowl/types"
+)
+
+const testRouterFlags = HandleOPTIONS | HandleMethodNotAllowed | RedirectTrailingSlash
+
+// linearRouter matches routes with a linear regex scan as the router did
+// before the route tree. It is kept as a reference for the tree.
+type linearRouter struct {
+	routes []*route
+}
+
+func (r *linearRouter) match(method, host, path string) (*route, types.StringMap, types.Flag) {
+	var pathTrailingSlash = path[len(path)-1] == '/'
+	var methodNotAllowed bool
+	var flag types.Flag
+
+	for _, route := range r.routes {
+		var p = path
+		var redirectTrailingSlash bool
+
+		// path is used unchanged for CONNECT requests
+		if method != CONNECT && route.flags.Has(RedirectTrailingSlash) {
+			// handle redirect for "/test" -> "/test/"
+			if route.trailingSlash && !pathTrailingSlash {
+				redirectTrailingSlash = true
+				p += "/"
+			}
+		}
+
+		params := make(types.StringMap)
+		if !route.matchHost(host, params) {
+			continue // fail
+		}
+
+		if route.rePath == nil {
+			// check static path
+			if p != route.path {
+				continue // fail
+			}
+		} else {
+			// match expensive regex
+			match := route.rePath.FindStringSubmatch(p)
+			if match == nil {
+				continue // fail
+			}
+			for i, name := range route.rePath.SubexpNames()[1:] {
+				route.setParam(params, name, match[i+1])
+			}
+		}
+
+		// match if any method allowed or method is explicitly defined
+		if len(route.methods) == 0 || helpers.IndexString(method, route.methods) != -1 {
+			if redirectTrailingSlash {
+				return nil, nil, RedirectTrailingSlash
+			}
+			return route, params, flag
+		}
+
+		if method == OPTIONS && route.flags.Has(HandleOPTIONS) {
+			return nil, nil, HandleOPTIONS
+		} else if route.flags.Has(HandleMethodNotAllowed) {
+			methodNotAllowed = true
+		}
+	}
+
+	// if method was not found
+	if methodNotAllowed {
+		return nil, nil, HandleMethodNotAllowed
+	}
+
+	return nil, nil, flag
+}
+
+// testRequest
+type testRequest struct {
+	method string
+	host   string
+	path   string
+}
+
+func (r testRequest) String() string {
+	return fmt.Sprintf("%s %s%s", r.method, r.host, r.path)
+}
+
+func testHandler(_ *Request) ResponseInterface {
+	return EmptyResponse()
+}
+
+func newTestRouters(fn func(r RouterInterface)) (*compiledRouter, *linearRouter) {
+	router := NewRouter(testRouterFlags)
+	fn(router)
+
+	compiled := newCompiledRouter()
+	compiled.addRouter(router)
+	compiled.compile()
+	return compiled, &linearRouter{routes: compiled.routes}
+}
+
+func newBenchmarkRouters() (*compiledRouter, *linearRouter, []testRequest) {
+	var requests []testRequest
+	compiled, linear := newTestRouters(func(r RouterInterface) {
+		for i := 0; i < 50; i++ {
+			prefix := fmt.Sprintf("/resource%d", i)
+			r.GET(prefix+"/", testHandler)
+			r.POST(prefix, testHandler)
+			r.GET(prefix+`/{id<\d+>}`, testHandler)
+			r.PUT(prefix+`/{id<\d+>}`, testHandler)
+			r.DELETE(prefix+`/{id<\d+>}`, testHandler)
+			r.GET(prefix+`/{id<\d+>}/edit`, testHandler)
+			r.GET(prefix+"/{slug}", testHandler)
+			r.GET(prefix+`/{id<\d+>}/items/{item}`, testHandler)
+			r.GET(prefix+"/search/{query<[^/]*>?all}", testHandler)
+			r.GET(prefix+"/files/{path<.+>}", testHandler)
+			r.GET(prefix+"/assets/{asset...}", testHandler)
+			r.GET(prefix+"/{from}-{to}", testHandler)
+
+			for _, path := range []string{
+				"", "/", "/42", "/42/", "/42/edit", "/slug", "/42/items/7", "/search/", "/search/go",
+				"/files/a/b/c.txt", "/assets/css/app.css", "/1-10", "/slug/unknown/path",
+			} {
+				for _, method := range []string{GET, POST, PUT, DELETE, OPTIONS} {
+					requests = append(requests, testRequest{method: method, path: prefix + path})
+				}
+			}
+		}
+	})
+	return compiled, linear, requests
+}
+
+func TestRouteTreeMatch(t *testing.T) {
+	compiled, linear := newTestRouters(func(r RouterInterface) {
+		r.GET("/users", testHandler).SetName("users")
+		r.GET(`/users/{id<\d+>}`, testHandler).SetName("user")
+		r.GET("/users/{name}", testHandler).SetName("user_by_name")
+		r.POST(`/users/{id<\d+>}`, testHandler).SetName("user_update")
+		r.GET("/raw/{path<.+>}.txt", testHandler).SetName("raw")
+		r.GET("/files/{path<.+>}", testHandler).SetName("files")
+		r.GET("/files/{path<.+>}/raw", testHandler).SetName("files_raw")
+		r.GET("/docs/", testHandler).SetName("docs")
+		r.GET(`/pages/{page<\d*>?1}`, testHandler).SetName("pages")
+		r.GET("/static/{asset...}", testHandler).SetName("static")
+		r.PUT("/items/{id}", testHandler).SetName("item")
+		r.GET("/tenant", testHandler).SetHost("{tenant}.example.com").SetName("tenant")
+		r.GET("/tenant", testHandler).SetName("tenant_default")
+	})
+
+	tests := []struct {
+		request testRequest
+		name    string
+		params  types.StringMap
+		flag    types.Flag
+	}{
+		{testRequest{GET, "", "/users"}, "users", types.StringMap{}, 0},
+		{testRequest{GET, "", "/users/42"}, "user", types.StringMap{"id": "42"}, 0},
+		{testRequest{GET, "", "/users/bob"}, "user_by_name", types.StringMap{"name": "bob"}, 0},
+		{testRequest{POST, "", "/users/42"}, "user_update", types.StringMap{"id": "42"}, 0},
+		{testRequest{DELETE, "", "/users/42"}, "", nil, HandleMethodNotAllowed},
+		{testRequest{OPTIONS, "", "/users/42"}, "", nil, HandleOPTIONS},
+		{testRequest{GET, "", "/users/"}, "", nil, 0},
+		{testRequest{GET, "", "/raw/a/b.txt"}, "raw", types.StringMap{"path": "a/b"}, 0},
+		{testRequest{GET, "", "/files/a/b/c"}, "files", types.StringMap{"path": "a/b/c"}, 0},
+		{testRequest{GET, "", "/files/a/raw"}, "files", types.StringMap{"path": "a/raw"}, 0},
+		{testRequest{GET, "", "/docs"}, "", nil, RedirectTrailingSlash},
+		{testRequest{GET, "", "/docs/"}, "docs", types.StringMap{}, 0},
+		{testRequest{CONNECT, "", "/docs"}, "", nil, 0},
+		{testRequest{GET, "", "/pages/"}, "pages", types.StringMap{"page": "1"}, 0},
+		{testRequest{GET, "", "/pages/7"}, "pages", types.StringMap{"page": "7"}, 0},
+		{testRequest{GET, "", "/pages/x"}, "", nil, 0},
+		{testRequest{GET, "", "/static/css/app.css"}, "static", types.StringMap{"asset": "css/app.css"}, 0},
+		{testRequest{GET, "", "/static/"}, "static", types.StringMap{"asset": ""}, 0},
+		{testRequest{GET, "", "/items/1"}, "", nil, HandleMethodNotAllowed},
+		{testRequest{OPTIONS, "", "/items/1"}, "", nil, HandleOPTIONS},
+		{testRequest{GET, "acme.example.com", "/tenant"}, "tenant", types.StringMap{"tenant": "acme"}, 0},
+		{testRequest{GET, "acme.example.com:8080", "/tenant"}, "tenant", types.StringMap{"tenant": "acme"}, 0},
+		{testRequest{GET, "example.org", "/tenant"}, "tenant_default", types.StringMap{}, 0},
+		{testRequest{GET, "", "/missing"}, "", nil, 0},
+	}
+
+	for _, tt := range tests {
+		for _, m := range []interface {
+			match(method, host, path string) (*route, types.StringMap, types.Flag)
+		}{compiled, linear} {
+			route, params, flag := m.match(tt.request.method, tt.request.host, tt.request.path)
+			name := ""
+			if route != nil {
+				name = route.name
+			}
+			if name != tt.name || !reflect.DeepEqual(params, tt.params) || flag != tt.flag {
+				t.Errorf("%T: %s = (%q, %v, %d), want (%q, %v, %d)",
+					m, tt.request, name, params, flag, tt.name, tt.params, tt.flag)
+			}
+		}
+	}
+}
+
+func TestRouteTreeEquivalence(t *testing.T) {
+	compiled, linear, requests := newBenchmarkRouters()
+	if n := len(compiled.routes); n != 600 {
+		t.Fatalf("got %d routes, want 600", n)
+	}
+
+	for _, r := range requests {
+		wantRoute, wantParams, wantFlag := linear.match(r.method, r.host, r.path)
+		route, params, flag := compiled.match(r.method, r.host, r.path)
+		if route != wantRoute || !reflect.DeepEqual(params, wantParams) || flag != wantFlag {
+			t.Errorf("%s = (%v, %v, %d), want (%v, %v, %d)", r, route, params, flag, wantRoute, wantParams, wantFlag)
+		}
+	}
+}
+
+func BenchmarkRouteTree(b *testing.B) {
+	compiled, _, requests := newBenchmarkRouters()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r := requests[i%len(requests)]
+		compiled.match(r.method, r.host, r.path)
+	}
+}
+
+func BenchmarkRouteLinear(b *testing.B) {
+	_, linear, requests := newBenchmarkRouters()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r := requests[i%len(requests)]
+		linear.match(r.method, r.host, r.path)
+	}
+}
@@ -2,6 +2,7 @@ package helpers
 
 import (
 	"regexp"
+	"regexp/syntax"
 )
 
 func ReplaceAllStringSubmatchFunc(re *regexp.Regexp, s string, repl func(match []string, i int) string) string {
@@ -19,3 +20,40 @@ func ReplaceAllStringSubmatchFunc(re *regexp.Regexp, s string, repl func(match [
 	}
 	return result + s[lastIndex:]
 }
+
+// RegexpCanMatchRune reports whether the expression may consume rune r.
+// The check is conservative: invalid expressions are reported as matching.
+func RegexpCanMatchRune(expr string, r rune) bool {
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return true
+	}
+	return syntaxCanMatchRune(re, r)
+}
+
+func syntaxCanMatchRune(re *syntax.Regexp, r rune) bool {
+	switch re.Op {
+	case syntax.OpAnyChar:
+		return true
+	case syntax.OpAnyCharNotNL:
+		return r != '\n'
+	case syntax.OpLiteral:
+		for _, c := range re.Rune {
+			if c == r {
+				return true
+			}
+		}
+	case syntax.OpCharClass:
+		for i := 0; i+1 < len(re.Rune); i += 2 {
+			if re.Rune[i] <= r && r <= re.Rune[i+1] {
+				return true
+			}
+		}
+	}
+	for _, sub := range re.Sub {
+		if syntaxCanMatchRune(sub, r) {
+			return true
+		}
+	}
+	return false
+}
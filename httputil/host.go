@@ -0,0 +1,12 @@
+package httputil
+
+import (
+	"net"
+)
+
+func StripPort(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
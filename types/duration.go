@@ -0,0 +1,33 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Duration is time.Duration that is encoded to JSON as a string like "1m30s".
+type Duration time.Duration
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON accepts only duration strings, as bare numbers are ambiguous
+// about their unit.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var v string
+	if err := json.Unmarshal(b, &v); err != nil {
+		return fmt.Errorf("types: invalid duration %s, expected string like \"30s\"", b)
+	}
+	duration, err := time.ParseDuration(v)
+	if err != nil {
+		return err
+	}
+	*d = Duration(duration)
+	return nil
+}
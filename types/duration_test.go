@@ -0,0 +1,28 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestDurationUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		json string
+		want Duration
+		err  bool
+	}{
+		{`"30s"`, Duration(30 * time.Second), false},
+		{`"1m30s"`, Duration(90 * time.Second), false},
+		{`30`, 0, true},
+		{`"30"`, 0, true},
+		{`null`, 0, true},
+	}
+	for _, tt := range tests {
+		var d Duration
+		err := json.Unmarshal([]byte(tt.json), &d)
+		if (err != nil) != tt.err || d != tt.want {
+			t.Errorf("Unmarshal(%s) = (%s, %v), want %s", tt.json, d, err, tt.want)
+		}
+	}
+}